@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// maxBaselineLine is the longest line loadBaseline accepts, far above any real sentence.
+const maxBaselineLine = 16 << 20
+
+// loadBaseline reads the sentences of an existing corpus at path, one per line as written by the
+// text format, into a set. Lines are trimmed and blank ones are skipped.
+func loadBaseline(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	baseline := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBaselineLine)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			baseline[line] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
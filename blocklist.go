@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// loadBlocklist compiles the regular expressions listed in the file at path, one per line.
+// Blank lines and lines starting with # are skipped. Every invalid pattern is reported with
+// its line number, so a broken file is fixed in one pass.
+func loadBlocklist(path string) ([]*regexp.Regexp, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var patterns []*regexp.Regexp
+	var problems []string
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		re, err := regexp.Compile(line)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s:%d: %v", path, lineNumber, err))
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid patterns:\n%s", strings.Join(problems, "\n"))
+	}
+	return patterns, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ljg-cqu/txt-sentencers_cn/sentencer"
+)
+
+// lengthBucket is a range of sentence lengths in runes; max 0 means unbounded.
+type lengthBucket struct {
+	name string
+	max  int
+}
+
+// lengthBuckets are the ranges -bucket counts sentences by, in increasing order.
+var lengthBuckets = []lengthBucket{{"1-10", 10}, {"11-30", 30}, {"31-60", 60}, {"61+", 0}}
+
+// bucketOf returns the index in lengthBuckets of the bucket text's length falls into.
+func bucketOf(text string) int {
+	n := utf8.RuneCountInString(text)
+	for i, b := range lengthBuckets {
+		if b.max == 0 || n <= b.max {
+			return i
+		}
+	}
+	return len(lengthBuckets) - 1
+}
+
+// bucketGroups splits the sentences of each group by length bucket, returning one list of groups
+// per bucket with the files in their original order.
+func bucketGroups(groups []fileSentences) [][]fileSentences {
+	buckets := make([][]fileSentences, len(lengthBuckets))
+	for _, g := range groups {
+		split := make([][]sentencer.Sentence, len(lengthBuckets))
+		for _, s := range g.sentences {
+			i := bucketOf(s.Text)
+			split[i] = append(split[i], s)
+		}
+		for i, sentences := range split {
+			if len(sentences) > 0 {
+				buckets[i] = append(buckets[i], fileSentences{path: g.path, sentences: sentences})
+			}
+		}
+	}
+	return buckets
+}
+
+// bucketCounts formats the number of sentences in each bucket, e.g. "1-10: 4, 11-30: 2, ...".
+func bucketCounts(buckets [][]fileSentences) string {
+	parts := make([]string, len(buckets))
+	for i, b := range buckets {
+		parts[i] = fmt.Sprintf("%s: %d", lengthBuckets[i].name, countSentences(b))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// bucketPath returns the path of a bucket's file next to the output path: out_sc.txt becomes
+// out_sc.len1-10.txt.
+func bucketPath(path string, bucket int) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".len" + lengthBuckets[bucket].name + ext
+}
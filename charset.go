@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// hanCounter tallies how often each Han character occurs in the Chinese sentences.
+type hanCounter map[rune]int
+
+// add counts the Han characters of the given sentences.
+func (c hanCounter) add(sentences []string) {
+	for _, s := range sentences {
+		for _, r := range s {
+			if unicode.Is(unicode.Han, r) {
+				c[r]++
+			}
+		}
+	}
+}
+
+// addStarts counts the first Han character of each of the given sentences, skipping any opening
+// quotes or other characters before it, for -start-index.
+func (c hanCounter) addStarts(sentences []string) {
+	for _, s := range sentences {
+		for _, r := range s {
+			if unicode.Is(unicode.Han, r) {
+				c[r]++
+				break
+			}
+		}
+	}
+}
+
+// indexLines returns a "character<TAB>count" line for every counted character in code point
+// order, which for Han characters roughly follows the radical order of dictionaries.
+func (c hanCounter) indexLines() []string {
+	chars := make([]rune, 0, len(c))
+	for r := range c {
+		chars = append(chars, r)
+	}
+	sort.Slice(chars, func(i, j int) bool { return chars[i] < chars[j] })
+	lines := make([]string, len(chars))
+	for i, r := range chars {
+		lines[i] = fmt.Sprintf("%c\t%d", r, c[r])
+	}
+	return lines
+}
+
+// sorted returns the counted characters by descending frequency, ties in code point order.
+func (c hanCounter) sorted() []rune {
+	chars := make([]rune, 0, len(c))
+	for r := range c {
+		chars = append(chars, r)
+	}
+	sort.Slice(chars, func(i, j int) bool {
+		if c[chars[i]] != c[chars[j]] {
+			return c[chars[i]] > c[chars[j]]
+		}
+		return chars[i] < chars[j]
+	})
+	return chars
+}
+
+// writeCharset writes one unique Han character per line, most frequent first, followed by a tab
+// and its count when withCounts is set. It is written like the sentence outputs, so
+// -max-output-bytes applies and -chunk-on-overflow may split it; the paths written are returned.
+func writeCharset(path string, c hanCounter, withCounts bool, opts outputOptions) ([]string, error) {
+	chars := c.sorted()
+	return writeChunks(path, len(chars), 0, opts, func(w io.Writer, start, end int) error {
+		var b strings.Builder
+		if withCounts {
+			b.WriteString(schemaHeader + "\n")
+		}
+		for _, r := range chars[start:end] {
+			if withCounts {
+				fmt.Fprintf(&b, "%c\t%d\n", r, c[r])
+			} else {
+				fmt.Fprintf(&b, "%c\n", r)
+			}
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	})
+}
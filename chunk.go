@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/transform"
+)
+
+// maxOutputBytes, set with -max-output-bytes, is the largest size in bytes of every file written
+// by writeChunks; 0 means no limit. An output over it is removed and fails, unless
+// chunkOnOverflow (-chunk-on-overflow) splits it into chunks that fit.
+var (
+	maxOutputBytes  int64
+	chunkOnOverflow bool
+)
+
+// chunkPath returns the path of the 1-based chunk index of the output path, numbered before the
+// extension with four zero-padded digits: out_sc.txt becomes out_sc.0001.txt.
+func chunkPath(path string, index int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%04d%s", strings.TrimSuffix(path, ext), index, ext)
+}
+
+// writeChunks writes n items, rendered by render, to files with opts: all of them to path when
+// size is not positive, or otherwise to consecutive chunk files of at most size items each, the
+// first holding items [0, size). At least one chunk is written, so an empty output still produces
+// a file. The returned paths are those written, in order.
+//
+// With maxOutputBytes set, a file over it is removed and reported as an error. With
+// chunkOnOverflow, each chunk instead holds as many items as fit in maxOutputBytes (and at most
+// size), as computed by measureItems; an output that fits whole in path without -chunk is left
+// unnumbered.
+func writeChunks(path string, n, size int, opts outputOptions, render func(w io.Writer, start, end int) error) ([]string, error) {
+	write := func(path string, start, end int) error {
+		err := writeOutputFile(path, opts, func(w io.Writer) error {
+			return render(w, start, end)
+		})
+		if err != nil {
+			return err
+		}
+		if err := checkOutputSize(path); err != nil {
+			os.Remove(path)
+			return err
+		}
+		return nil
+	}
+
+	var sizes *itemSizes
+	if maxOutputBytes > 0 && chunkOnOverflow {
+		var err error
+		if sizes, err = measureItems(path, n, opts, render); err != nil {
+			return nil, err
+		}
+		if size <= 0 && sizes.total() <= maxOutputBytes {
+			sizes = nil // Fits whole
+		} else if size <= 0 {
+			size = n
+		}
+	}
+	if size <= 0 {
+		if err := write(path, 0, n); err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+	}
+
+	var paths []string
+	for start, index := 0, 1; start < n || index == 1; index++ {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		if sizes != nil {
+			var err error
+			if end, err = sizes.fit(path, start, end); err != nil {
+				return paths, err
+			}
+		}
+		chunk := chunkPath(path, index)
+		err := write(chunk, start, end)
+		for err != nil && sizes != nil && end-start > 1 && errors.Is(err, errOutputSize) {
+			end-- // Only for outputs whose size is not the sum measureItems computes
+			err = write(chunk, start, end)
+		}
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, chunk)
+		start = end
+	}
+	return paths, nil
+}
+
+// itemSizes holds the encoded size of every item of an output alone, and the bytes each item
+// shares with the one before it, such as the brackets of a json array or a -group-by-file header.
+type itemSizes struct {
+	empty    int64   // Size of the output without items
+	items    []int64 // Size of the output of item i alone
+	overlaps []int64 // items[i-1] + items[i] less the size of the output of both; 0 for i = 0
+}
+
+// measureItems renders every item and every pair of adjacent items of an output once in memory.
+// The size of a run of items is then the sum of their own sizes less their overlaps, which is
+// exact for text, ndjson, json and the line outputs, so their chunks are each written once.
+func measureItems(path string, n int, opts outputOptions, render func(w io.Writer, start, end int) error) (*itemSizes, error) {
+	measure := func(start, end int) (int64, error) {
+		return measureOutput(opts, func(w io.Writer) error {
+			return render(w, start, end)
+		})
+	}
+	empty, err := measure(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	sizes := &itemSizes{empty: empty, items: make([]int64, n), overlaps: make([]int64, n)}
+	for i := 0; i < n; i++ {
+		if sizes.items[i], err = measure(i, i+1); err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			pair, err := measure(i-1, i+1)
+			if err != nil {
+				return nil, err
+			}
+			sizes.overlaps[i] = sizes.items[i-1] + sizes.items[i] - pair
+		}
+	}
+	return sizes, nil
+}
+
+// total returns the computed size of the output of all items.
+func (s *itemSizes) total() int64 {
+	if len(s.items) == 0 {
+		return s.empty
+	}
+	total := s.items[0]
+	for i := 1; i < len(s.items); i++ {
+		total += s.items[i] - s.overlaps[i]
+	}
+	return total
+}
+
+// fit returns the end of the longest run of items from start, up to end, whose computed size is
+// within maxOutputBytes. A run needs at least one item, which must fit on its own.
+func (s *itemSizes) fit(path string, start, end int) (int, error) {
+	if start == end {
+		if s.empty > maxOutputBytes {
+			return 0, fmt.Errorf("%s is over -max-output-bytes %d even without sentences", path, maxOutputBytes)
+		}
+		return end, nil
+	}
+	if s.items[start] > maxOutputBytes {
+		return 0, fmt.Errorf("%s: sentence %d alone is over -max-output-bytes %d", path, start+1, maxOutputBytes)
+	}
+	fits, bytes := start+1, s.items[start]
+	for fits < end && bytes+s.items[fits]-s.overlaps[fits] <= maxOutputBytes {
+		bytes += s.items[fits] - s.overlaps[fits]
+		fits++
+	}
+	return fits, nil
+}
+
+// countingWriter counts the bytes written to it and discards them.
+type countingWriter struct{ n int64 }
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// measureOutput returns the size in bytes of the output written by write once encoded with
+// opts.encoding, without creating any file.
+func measureOutput(opts outputOptions, write func(w io.Writer) error) (int64, error) {
+	encoder, err := newOutputEncoder(opts.encoding)
+	if err != nil {
+		return 0, err
+	}
+	counter := &countingWriter{}
+	if encoder == nil {
+		err := write(counter)
+		return counter.n, err
+	}
+	transcoder := transform.NewWriter(counter, encoder)
+	if err := write(transcoder); err != nil {
+		return 0, err
+	}
+	if err := transcoder.Close(); err != nil {
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+// errOutputSize is wrapped by the errors of files over maxOutputBytes.
+var errOutputSize = errors.New("over -max-output-bytes")
+
+// checkOutputSize reports an error when the file at path is over maxOutputBytes.
+func checkOutputSize(path string) error {
+	if maxOutputBytes <= 0 {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() > maxOutputBytes {
+		return fmt.Errorf("%s is %d bytes, %w %d", path, info.Size(), errOutputSize, maxOutputBytes)
+	}
+	return nil
+}
+
+// sliceGroups returns the sentences [start, end) of the concatenated groups, keeping each
+// sentence in a group for its file so -group-by-file headers are repeated in every chunk.
+func sliceGroups(groups []fileSentences, start, end int) []fileSentences {
+	var sliced []fileSentences
+	offset := 0
+	for _, g := range groups {
+		from, to := start-offset, end-offset
+		offset += len(g.sentences)
+		if from < 0 {
+			from = 0
+		}
+		if to > len(g.sentences) {
+			to = len(g.sentences)
+		}
+		if from < to {
+			sliced = append(sliced, fileSentences{path: g.path, sentences: g.sentences[from:to]})
+		}
+	}
+	return sliced
+}
+
+// describePaths names the files an output was written to: the single path, or the first and last
+// chunk with their count.
+func describePaths(paths []string) string {
+	if len(paths) == 1 {
+		return paths[0]
+	}
+	return fmt.Sprintf("%s ... %s (%d files)", paths[0], paths[len(paths)-1], len(paths))
+}
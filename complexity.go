@@ -0,0 +1,54 @@
+package main
+
+import (
+	"math"
+	"unicode"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// Character difficulty grades, taken from the GB2312 standard, whose level-1 set holds the 3755
+// most frequently used characters and whose level-2 set the 3008 next most common ones. GBK
+// encodes GB2312 unchanged, so a character's grade follows from the lead byte of its GBK code.
+const (
+	gradeCommon = 0.0 // GB2312 level 1, lead bytes 0xB0-0xD7
+	gradeLess   = 0.5 // GB2312 level 2, lead bytes 0xD8-0xF7
+	gradeRare   = 1.0 // Outside GB2312: GBK extensions and characters GBK cannot encode
+)
+
+// characterGrade returns the difficulty grade of the Han character r.
+func characterGrade(r rune) float64 {
+	encoded, err := simplifiedchinese.GBK.NewEncoder().String(string(r))
+	if err != nil || len(encoded) != 2 {
+		return gradeRare
+	}
+	lead, trail := encoded[0], encoded[1]
+	if trail < 0xA1 || trail > 0xFE {
+		return gradeRare // GBK extension area
+	}
+	switch {
+	case lead >= 0xB0 && lead <= 0xD7:
+		return gradeCommon
+	case lead >= 0xD8 && lead <= 0xF7:
+		return gradeLess
+	default:
+		return gradeRare
+	}
+}
+
+// complexityScore rates how hard text is to read as the average grade of its Han characters,
+// from 0 when they are all among the most common ones to 1 when they are all rare, rounded to
+// two decimals. ok is false when text has no Han character.
+func complexityScore(text string) (score float64, ok bool) {
+	total, han := 0.0, 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			total += characterGrade(r)
+			han++
+		}
+	}
+	if han == 0 {
+		return 0, false
+	}
+	return math.Round(total/float64(han)*100) / 100, true
+}
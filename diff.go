@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ljg-cqu/txt-sentencers_cn/sentencer"
+)
+
+// diffContext is how many unchanged lines -show-diff prints around each change.
+const diffContext = 3
+
+// diffOp is one line of a line diff: kept in both sides (' '), removed from the first ('-') or
+// added by the second ('+').
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// diffLines returns the edit script turning a into b, from their longest common subsequence.
+// It takes O(len(a)*len(b)) time and memory, which is fine for the samples -show-diff compares.
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// writeUnifiedDiff writes the diff between a and b in unified format, with diffContext lines
+// of context around each hunk. Nothing is written when they are equal.
+func writeUnifiedDiff(w io.Writer, nameA, nameB string, a, b []string) {
+	ops := diffLines(a, b)
+	header := false
+	for start := 0; start < len(ops); {
+		// Find the next change and extend the hunk until changes are more than twice the
+		// context apart.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		end := first
+		for k := first; k < len(ops) && k-end <= 2*diffContext; k++ {
+			if ops[k].kind != ' ' {
+				end = k + 1
+			}
+		}
+		from := first - diffContext
+		if from < start {
+			from = start
+		}
+		to := end + diffContext
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		if !header {
+			fmt.Fprintf(w, "--- %s\n+++ %s\n", nameA, nameB)
+			header = true
+		}
+		lineA, lineB := 1, 1
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				lineA++
+			}
+			if op.kind != '-' {
+				lineB++
+			}
+		}
+		countA, countB := 0, 0
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(lineA, countA), hunkRange(lineB, countB))
+		for _, op := range ops[from:to] {
+			fmt.Fprintf(w, "%c%s\n", op.kind, op.text)
+		}
+		start = to
+	}
+}
+
+// hunkRange formats the start,count range of a hunk header. An empty range names the line
+// before it, as diff -u does.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// printDiffs prints, for every input, a unified diff between its first maxLines raw lines and the
+// sentences cleaned from them, to show what the pipeline changes.
+func printDiffs(paths []string, maxLines int, opts sentencer.Options) error {
+	for _, path := range paths {
+		file, err := opts.Open(path)
+		if err != nil {
+			return err
+		}
+		var raw []string
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+		scanner.Split(sentencer.ScanLines)
+		for len(raw) < maxLines && scanner.Scan() {
+			raw = append(raw, scanner.Text())
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+
+		var cleaned []string
+		for _, s := range sentencer.ProcessText(strings.Join(raw, "\n"), opts).Sentences {
+			cleaned = append(cleaned, s.Text)
+		}
+		writeUnifiedDiff(os.Stdout, path, path+" (cleaned)", raw, cleaned)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"unicode"
+
+	"github.com/ljg-cqu/txt-sentencers_cn/sentencer"
+)
+
+// charDistribution counts the non-whitespace characters of the extracted sentences per script.
+type charDistribution struct {
+	chinese, english, other int
+}
+
+// add counts the characters of the given sentences.
+func (d *charDistribution) add(sentences []sentencer.Sentence) {
+	for _, s := range sentences {
+		for _, r := range s.Text {
+			if unicode.IsSpace(r) {
+				continue
+			}
+			switch sentencer.ScriptOf(r) {
+			case sentencer.ScriptChinese:
+				d.chinese++
+			case sentencer.ScriptEnglish:
+				d.english++
+			default:
+				d.other++
+			}
+		}
+	}
+}
+
+// distributionCategory is the chart-ready summary of one script category.
+type distributionCategory struct {
+	Characters int     `json:"characters"`
+	Percent    float64 `json:"percent"`
+}
+
+// distributionReport is the JSON document written by -distribution.
+type distributionReport struct {
+	SchemaVersion   int                  `json:"schema_version"`
+	TotalCharacters int                  `json:"total_characters"`
+	Chinese         distributionCategory `json:"chinese"`
+	English         distributionCategory `json:"english"`
+	Other           distributionCategory `json:"other"`
+}
+
+// report converts the counts into totals and percentages. Percentages are 0 for empty input.
+func (d charDistribution) report() distributionReport {
+	total := d.chinese + d.english + d.other
+	category := func(n int) distributionCategory {
+		c := distributionCategory{Characters: n}
+		if total > 0 {
+			c.Percent = float64(n) * 100 / float64(total)
+		}
+		return c
+	}
+	return distributionReport{
+		SchemaVersion:   schemaVersion,
+		TotalCharacters: total,
+		Chinese:         category(d.chinese),
+		English:         category(d.english),
+		Other:           category(d.other),
+	}
+}
+
+// writeDistribution writes the distribution report as indented JSON to path.
+func writeDistribution(path string, d charDistribution) error {
+	data, err := json.MarshalIndent(d.report(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutputData(path, append(data, '\n'))
+}
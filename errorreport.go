@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+// Stages of a fileError.
+const (
+	stageRead  = "read"  // Reading or splitting the input
+	stageWrite = "write" // Creating or writing one of its outputs
+)
+
+// fileError is an input file that failed, for -error-report.
+type fileError struct {
+	Path  string `json:"path"`
+	Stage string `json:"stage"`
+	Error string `json:"error"`
+}
+
+// errorReport collects the input files that failed during a batch, in input order.
+type errorReport struct {
+	SchemaVersion int         `json:"schema_version"`
+	Errors        []fileError `json:"errors"`
+}
+
+// add records that the input at path failed in stage with err.
+func (r *errorReport) add(path, stage string, err error) {
+	r.Errors = append(r.Errors, fileError{Path: path, Stage: stage, Error: err.Error()})
+}
+
+// write saves the report as indented JSON to path.
+func (r *errorReport) write(path string) error {
+	if r.Errors == nil {
+		r.Errors = []fileError{}
+	}
+	r.SchemaVersion = schemaVersion
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutputData(path, append(data, '\n'))
+}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestDefaultFileMode(t *testing.T) {
+	// Under umask 0 the mode is exactly the one output files are created with
+	defer syscall.Umask(syscall.Umask(0))
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", sampleInput)
+	mustRun(t, 0, "a.txt")
+	info, err := os.Stat("a_sc.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("a_sc.txt has mode %v, want 0644", info.Mode().Perm())
+	}
+}
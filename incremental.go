@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// incrementalState is the -state file of -incremental runs: the fingerprint of every input file
+// processed so far, together with the flags of the run that processed them.
+type incrementalState struct {
+	Config string                     `json:"config"` // runConfig of the run that wrote the state
+	Files  map[string]fileFingerprint `json:"files"`  // Absolute input path -> fingerprint
+}
+
+// fileFingerprint identifies the version of an input file that was processed, and the outputs
+// written from it.
+type fileFingerprint struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Outputs []string  `json:"outputs"`
+}
+
+// loadState reads the state file at path for a run with the given config. A missing file, or one
+// written with different flags, yields an empty state, so every input is processed again.
+func loadState(path, config string) (*incrementalState, error) {
+	state := &incrementalState{Config: config, Files: map[string]fileFingerprint{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved incrementalState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	if saved.Config == config && saved.Files != nil {
+		state.Files = saved.Files
+	}
+	return state, nil
+}
+
+// unchanged reports whether the input at path has the size and modification time recorded for it
+// and all outputs written from it still exist. Inputs that changed are forgotten.
+func (s *incrementalState) unchanged(path string) bool {
+	key := stateKey(path)
+	recorded, ok := s.Files[key]
+	if !ok {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err == nil && info.Size() == recorded.Size && info.ModTime().Equal(recorded.ModTime) {
+		for _, output := range recorded.Outputs {
+			if _, err := os.Stat(output); err != nil {
+				delete(s.Files, key)
+				return false
+			}
+		}
+		return true
+	}
+	delete(s.Files, key)
+	return false
+}
+
+// outputs returns the outputs recorded for the input at path.
+func (s *incrementalState) outputs(path string) []string {
+	return s.Files[stateKey(path)].Outputs
+}
+
+// record saves the current fingerprint of the input at path with the outputs written from it. URL
+// inputs are not recorded.
+func (s *incrementalState) record(path string, outputs []string) error {
+	if isURL(path) {
+		return nil // URLs have no modification time to compare; they are fetched on every run
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	s.Files[stateKey(path)] = fileFingerprint{Size: info.Size(), ModTime: info.ModTime(), Outputs: outputs}
+	return nil
+}
+
+// save writes the state as indented JSON to path.
+func (s *incrementalState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// stateKey returns the key of an input in the state file, its absolute path when it can be
+// determined, so relative and absolute arguments naming one file share an entry.
+func stateKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// runConfig describes the flags set on the command line, except -incremental and -state, as
+// "name=value" pairs. Outputs written under different flags may differ, so a state is only reused
+// by runs with the same config.
+func runConfig() string {
+	var pairs []string
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "incremental" && f.Name != "state" {
+			pairs = append(pairs, f.Name+"="+f.Value.String())
+		}
+	})
+	return strings.Join(pairs, " ")
+}
+
+// aggregateFlags are the flags of outputs gathered from all inputs. They are rewritten from the
+// inputs processed by the run alone, so a skipped input would drop out of them.
+var aggregateFlags = []string{"combined", "single-out", "bilingual", "numeric-out", "segment", "rejected",
+	"aligned", "extract-numbers", "pairs", "changed-only", "charset", "start-index", "distribution", "bucket-files"}
+
+// aggregateFlagsSet returns the aggregateFlags set on the command line to a non-empty value, as
+// "-name".
+func aggregateFlagsSet() []string {
+	var set []string
+	flag.Visit(func(f *flag.Flag) {
+		value := f.Value.String()
+		for _, name := range aggregateFlags {
+			if f.Name == name && value != "" && value != "false" {
+				set = append(set, "-"+name)
+			}
+		}
+	})
+	return set
+}
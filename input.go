@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// openPDF extracts the plain text of a PDF file. It is only set in builds with the pdf tag (see
+// pdf.go), which keeps the PDF library out of the default binary.
+var openPDF func(path string) (io.ReadCloser, error)
+
+// errNoPDFSupport is reported for PDF inputs by binaries built without the pdf tag.
+var errNoPDFSupport = errors.New("PDF input needs a binary built with -tags pdf")
+
+// isPDF reports whether path names a local PDF file by its extension.
+func isPDF(path string) bool {
+	return !isURL(path) && strings.EqualFold(filepath.Ext(path), ".pdf")
+}
+
+// isURL reports whether an input argument is an http or https URL rather than a file path.
+func isURL(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// inputOpener opens the inputs of a run: files, PDF files and URLs.
+type inputOpener struct {
+	client   *http.Client // Client fetching URL inputs, with the -url-timeout
+	maxBytes int64        // Largest URL response body accepted
+}
+
+// open opens an input for the sentencer, extracting the text of PDF files and fetching URLs.
+func (o inputOpener) open(path string) (io.ReadCloser, error) {
+	if isURL(path) {
+		return o.fetch(path)
+	}
+	if !isPDF(path) {
+		return os.Open(path)
+	}
+	if openPDF == nil {
+		return nil, errNoPDFSupport
+	}
+	return openPDF(path)
+}
+
+// fetch requests url and returns its response body, which fails once it exceeds o.maxBytes. The
+// transport asks for gzip and decompresses it transparently.
+func (o inputOpener) fetch(url string) (io.ReadCloser, error) {
+	resp, err := o.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	return &cappedBody{ReadCloser: resp.Body, remaining: o.maxBytes, url: url}, nil
+}
+
+// cappedBody is a response body failing with an error once more than remaining bytes were read.
+type cappedBody struct {
+	io.ReadCloser
+	remaining int64
+	url       string
+}
+
+func (b *cappedBody) Read(p []byte) (int, error) {
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, fmt.Errorf("response of %s exceeds -url-max-bytes", b.url)
+	}
+	return n, err
+}
+
+// urlBaseName returns the name a URL input's output is derived from: the last element of its path,
+// or its host when the path is empty, as in https://example.com/.
+func urlBaseName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "input"
+	}
+	if name := path.Base(u.Path); name != "/" && name != "." {
+		return name
+	}
+	return u.Hostname()
+}
+
+// newSegmenter returns a function cutting Chinese text into words. It is only set in builds with
+// the segment tag (see segment.go), which keeps the dictionary-based segmenter out of the default
+// binary.
+var newSegmenter func() (func(text string) []string, error)
@@ -1,24 +1,36 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
+	"time"
 
+	"github.com/ljg-cqu/txt-sentencers_cn/sentencer"
 	"github.com/sqweek/dialog" // Import sqweek/dialog for file selection
 )
 
 /*
 Description:
 This program processes text files by inserting newlines after Chinese punctuation and removing empty lines. The output is saved as a new file with a "_sc" suffix added to the original file name.
+The splitting itself lives in the sentencer package, which can be used on in-memory text without this command.
 
 Features:
-- Adds newlines after Chinese punctuation marks using regex.
+- Adds newlines after Chinese punctuation marks using regex, including the vertical forms (︐︒︖...) of vertical layout.
 - Removes empty lines from the content for cleanliness.
 - Allows file selection via a GUI and writes processed content to an output file.
+- Skips empty input files with a warning and rejects likely-binary files (many NUL bytes) with exit status 1.
+- Accepts input files (and http:// or https:// URLs) as arguments, or runs as an HTTP service with -serve.
+- Cleans, filters and routes the sentences into Chinese and English streams, written as text, json, ndjson,
+  sqlite or parquet, plus side outputs and reports for datasets. Run with -h for every flag.
 
 Workflow:
 1. User selects an input file through a GUI using sqweek/dialog.
@@ -26,68 +38,1103 @@ Workflow:
 3. Removes unnecessary empty lines from the processed content.
 4. Saves cleaned content in a new file with a "_sc" suffix in the same directory.
 5. Notifies the user after successful processing.
+
+Every json/ndjson record, -serve response and JSON report carries "schema_version", and the TSV side files
+start with a "# schema_version: N" comment line; see schemaVersion.
+
+Some features need build tags: -format sqlite ("go build -tags sqlite"), -format parquet ("-tags parquet,purego";
+without purego the Parquet library fails to link on Go 1.23 or later), PDF input ("-tags pdf") and -segment
+("-tags segment").
+
+The exit status is 0 on success, 1 when an input or output failed, 2 for invalid flags and 3 when -timeout
+expired.
 */
 
 func main() {
-	// Step 1: Use sqweek/dialog to let the user select the input file
-	inputFilePath, err := dialog.File().
-		Filter("Text Files", "txt").
-		Title("Select Input File").
-		Load()
-	if err != nil {
-		if err == dialog.Cancelled {
-			fmt.Println("File selection was cancelled.")
-		} else {
-			fmt.Println("Error selecting input file:", err)
+	os.Exit(run())
+}
+
+// exitTimeout is the exit status of a run cut short by -timeout.
+const exitTimeout = 3
+
+// run executes the command and returns its exit status: 0 on success, 1 when an input or output
+// failed, 2 for invalid flags and exitTimeout when -timeout expired.
+func run() int {
+	verbose := flag.Bool("v", false, "print per-file statistics and per-stage timings")
+	checkConfig := flag.Bool("check-config", false, "validate every flag value (terminators, -grep pattern, -blocklist file, modes, formats, ...) without reading any input, printing \"Configuration is valid.\" and exiting with 0, or the error and 2")
+	selfTest := flag.Bool("selftest", false, "run the bundled fixture through the pipeline under the default options and the main splitting flags (see selfTestCases), compare each run against its golden output and exit; output-only flags such as -format are not covered")
+	serveAddr := flag.String("serve", "", "run as an HTTP server on this address (e.g. :8080) instead of processing files: POST UTF-8 text to /process for the sentences as JSON, GET /healthz answers ok and GET /metrics serves the -metrics metrics")
+	serveMaxBytes := flag.Int64("serve-max-bytes", 10<<20, "with -serve, the largest request body accepted in bytes; larger bodies get status 413")
+	showDiff := flag.Int("show-diff", 0, "print a unified diff between the first N lines of each input and the sentences cleaned from them, and exit; time and memory grow with N squared")
+	debugMatches := flag.Int("debug-matches", 0, "print the split pattern and its matches, with rune offsets, in the first N lines of each input before cleaning, and exit")
+	outputFormat := flag.String("format", formatText, "output format: text, json (an array of {schema_version, text, lang, source_line} records), ndjson (one record per line), sqlite (see -db) or parquet (see -out), or a comma-separated list such as text,json written in one pass, the text output then always ending in .txt")
+	dbPath := flag.String("db", "", "with -format sqlite, the database file the sentences are written to as sentences(id, lang, text, source_file, source_line); replaced on every run (needs -tags sqlite)")
+	parquetPath := flag.String("out", "", "with -format parquet, the Parquet file the lang, text, source_file and source_line columns are written to (needs -tags parquet,purego)")
+	jsonFlat := flag.Bool("json-flat", false, "with -combined over several inputs, write -format json as one flat array of sentences instead of one {file, chinese, english, other} object per file")
+	jsonIndent := flag.Int("json-indent", 2, "spaces per indentation level for -format json (0 = compact)")
+	withEndType := flag.Bool("with-end-type", false, "add an end_type field (statement, exclamation, question, other or none, from the terminal punctuation) to json/ndjson records")
+	withID := flag.Bool("with-id", false, "add a stable id (first 16 hex digits of the sentence's SHA-256, ignoring -inline-tags) to json/ndjson records and as an id<TAB>text column in text output")
+	complexity := flag.Bool("complexity", false, "add a complexity score to the json/ndjson record of each Chinese sentence: the average difficulty of its Han characters, 0 for GB2312 level 1, 0.5 for level 2 and 1 for the rest")
+	withMatcher := flag.Bool("with-matcher", false, "add a matcher field to json/ndjson records naming what ended each sentence: punctuation, end, script-split, english-tokenizer, table-glyph, line-break or divider")
+	withCounts := flag.Bool("with-counts", false, "add runes and bytes fields (character count and UTF-8 length) to json/ndjson records")
+	outputEncoding := flag.String("output-encoding", encodingUTF8, "output file encoding: utf-8 or gbk; characters GBK cannot represent become the substitute character 0x1A")
+	splitEnum := flag.Bool("split-enum", true, "treat the enumeration comma 、 as a sentence boundary")
+	terminators := flag.String("terminators", "", "characters that end a sentence in any language, e.g. 。！？.!?, replacing the default punctuation set and -split-enum")
+	scriptSplit := flag.Bool("script-split", false, "also split where the text switches between Chinese and English, even without punctuation; digits, spaces and punctuation stay with the run they follow")
+	overlap := flag.String("overlap", sentencer.OverlapBoth, "where sentences mixing Chinese and English such as 你好world go: both (kept whole and counted as Chinese), split (cut where the script changes) or drop (kept whole, but each piece counts only for its own script)")
+	englishTokenizer := flag.Bool("english-tokenizer", false, "split English text into sentences with a rule-based tokenizer: a sentence ends after . ! or ? followed by a space and an upper-case letter, digit or Han character, but not after abbreviations, initials or decimals")
+	digits := flag.String("digits", sentencer.DigitsNeutral, "how digits are classified, for routing digit-only sentences and for -script-split: neutral (neither script), chinese or english; neutral is the default because digit-only sentences were never routed to Chinese, so it keeps the existing output")
+	alnumTokens := flag.String("alnum-tokens", sentencer.AlnumKeep, "how the digits of letters-and-digits tokens such as COVID19 are classified: keep (as English) or split (per -digits)")
+	zhSemicolon := flag.Bool("zh-semicolon", true, "split at the Chinese semicolon ； of the default marks (not -terminators)")
+	enSemicolon := flag.Bool("en-semicolon", false, "also split after an English semicolon followed by a space, so \"a; b\" becomes \"a;\" and \"b\"")
+	colon := flag.String("colon", sentencer.ColonHard, "how the colon ： of the default marks splits: hard (a boundary), soft (the clause it introduces runs on to the next 。！？) or none (never a boundary)")
+	trim := flag.String("trim", sentencer.TrimBoth, "whitespace trimmed from each sentence: both, right (keeping indentation) or none; whitespace-only sentences are always dropped")
+	tableGlyphs := flag.Bool("table-glyphs", true, "keep the table separators \\ | ︱ 丨 as text; when false they split and are dropped")
+	collapsePunct := flag.Bool("collapse-punct", false, "fold runs of an identical terminal mark such as ？？？ or !!! into one before splitting; runs of . are kept as an ellipsis")
+	wholeFile := flag.Bool("whole-file", false, "treat each input as one stream, ignoring line boundaries, and split on punctuation only; enabled automatically when every input is a PDF")
+	keepDividers := flag.Bool("keep-dividers", false, "emit divider lines, one mark repeated at least three times such as ————— or ＊＊＊, as a '---' marker (not with -whole-file)")
+	warnEmpty := flag.Bool("warn-empty", false, "warn about non-blank input lines that produced no sentence before -grep filtering (not with -whole-file)")
+	maxFragments := flag.Int("max-sentences-per-line", sentencer.DefaultMaxFragments, "maximum fragments produced from one input line before the rest is kept as one fragment and a warning is printed (0 = unlimited)")
+	keepUnits := flag.Bool("keep-units", false, "keep a number attached to its following measure unit (e.g. 3.5米) when a decimal point or separator such as . ， or 、 splits them; terminators such as 。 still end the sentence")
+	urlTimeout := flag.Duration("url-timeout", 30*time.Second, "time limit for fetching each http(s) URL input; URL outputs are named after the last element of the URL path")
+	urlMaxBytes := flag.Int64("url-max-bytes", 100<<20, "largest response body accepted from an http(s) URL input, in bytes")
+	outDir := flag.String("outdir", "", "write the per-file outputs into this directory instead of next to each input")
+	preservePaths := flag.Bool("preserve-paths", false, "with -outdir, mirror each input's relative directory so same-named files do not collide; without it a collision is an error")
+	combinedPath := flag.String("combined", "", "write the sentences of all input files into this one file instead of one _sc file per input")
+	singleOutPath := flag.String("single-out", "", "write the sentences of all inputs to this file as one blob joined by -record-join, besides the regular outputs; not split by -chunk")
+	recordJoin := flag.String("record-join", `\n`, "with -single-out, the separator between sentences (e.g. \\x1e or </s>)")
+	combinedSep := flag.String("combined-sep", "", "join adjacent Chinese and English fragments of one input line with this separator (e.g. \\t or |||) in text output")
+	combinedMode := flag.String("combined-mode", sentencer.CombinedModeLines, "layout of the combined text stream: lines (one sentence per line) or blocks (runs of one language under a '# [zh]' or '# [en]' label line); blocks cannot be combined with -combined-sep")
+	inlineTags := flag.Bool("inline-tags", false, "prefix each sentence of the text output with its language tag, [zh] or [en]; sentences of neither script get none")
+	groupByFile := flag.Bool("group-by-file", false, "precede each input file's sentences in the -combined text output with a '# === name ===' header line")
+	bucket := flag.Bool("bucket", false, "print the number of sentences per length bucket (1-10, 11-30, 31-60, 61+ runes)")
+	bucketFiles := flag.Bool("bucket-files", false, "also write the sentences of each length bucket to their own file next to the output, e.g. out_sc.len1-10.txt")
+	distributionPath := flag.String("distribution", "", "write the Chinese/English/other character distribution of the output as JSON to this file")
+	canonPunct := flag.Bool("canon-punct", false, "map ASCII punctuation , ! ? : ; ( ) in Chinese sentences to full-width forms, keeping separators between digits such as 1,000")
+	canonPunctEnglish := flag.Bool("canon-punct-en", false, "map full-width punctuation, and 。, in English sentences to ASCII forms")
+	digitForm := flag.String("digit-form", "", "rewrite ASCII, full-width and basic Chinese digits in one form: ascii, fullwidth or hanzi (digit by digit; English sentences get ascii); approximations, decimals and words such as 一些 are left alone (default: unchanged)")
+	unifyDashes := flag.Bool("unify-dashes", false, "before splitting, map runs of dash and hyphen variants next to Han characters to ——, one split mark, and others to -; a single - is kept")
+	straightQuotes := flag.Bool("straight-quotes", false, "map curly quotes and apostrophes to ASCII quotes in English sentences")
+	stripInvisible := flag.Bool("strip-invisible", false, "remove soft hyphens, zero-width spaces, directional marks, stray BOMs and other invisible format characters before splitting, keeping joiners inside emoji")
+	stripRanges := flag.Bool("strip-ranges", false, "remove spans enclosed by the -strip-range-pairs delimiters, such as 【注：...】, from each line before splitting; spans may nest and unclosed openers are kept")
+	stripRangePairs := flag.String("strip-range-pairs", "【】", "with -strip-ranges, the opening and closing delimiter of each pair in a row, e.g. 【】〔〕")
+	stripDialogue := flag.Bool("strip-dialogue", false, "remove the dialogue dash — or —— at the start of a sentence, as in ——你来了; dashes inside a sentence are kept")
+	ideographicSpace := flag.Bool("ideographic-space", false, "collapse runs of the full-width space U+3000 inside sentences and remove it at both ends, even under -trim right or none")
+	despaceCJK := flag.Bool("despace-cjk", false, "remove spaces between two Han characters, as inserted by OCR; spaces next to Latin text or digits are kept")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of input files processed concurrently; output is still reported in input order")
+	collapseAdjacent := flag.Bool("collapse-adjacent", false, "keep only the first of consecutive identical sentences")
+	dedup := flag.Bool("dedup", false, "keep only the first occurrence of each sentence within an input")
+	dedupNormalized := flag.Bool("dedup-normalized", false, "deduplicate ignoring case, trailing punctuation and repeated whitespace (implies -dedup)")
+	dedupWindow := flag.Int("dedup-window", 0, "deduplicate only within this many lines: drop a sentence seen on the same line or the N lines before it, every repeat restarting the window (implies -dedup)")
+	dedupCache := flag.Int("dedup-cache", 0, "with -dedup, remember only this many most recent sentences, bounding memory; repeats of older sentences are kept (0 = all)")
+	excludeExisting := flag.String("exclude-existing", "", "drop sentences already present in this baseline corpus file (one sentence per line), compared exactly after cleaning")
+	maxPunctRatio := flag.Float64("max-punct-ratio", 0, "drop sentences in which punctuation exceeds this fraction of the characters, e.g. 0.5; a sentence exactly at it is kept (0 = off)")
+	requireBalanced := flag.Bool("require-balanced", false, "drop sentences with unbalanced or badly nested brackets or quotes such as （ without ） or “ without ”; a ’ between letters is an apostrophe")
+	langHint := flag.String("lang-hint", sentencer.LangHintAuto, "language of the inputs: zh or en keeps only the sentences routed to that language (any Han character makes a sentence Chinese) and skips the other's stages; auto handles both")
+	target := flag.String("target", "", "keep only sentences predominantly in this language, zh or en, by comparing Han characters with English words, dropping everything else")
+	fromLine := flag.Int("from-line", 0, "process only the input lines from this 1-based line on (0 = from the start)")
+	toLine := flag.Int("to-line", 0, "process only the input lines up to and including this line, reading no further (0 = to the end)")
+	blocklistPath := flag.String("blocklist", "", "skip input lines matching any regular expression listed in this file, one per line, ignoring blank lines and lines starting with #")
+	grepPattern := flag.String("grep", "", "keep only sentences matching this regular expression")
+	grepInvert := flag.Bool("grep-invert", false, "with -grep, keep only sentences that do not match")
+	reverse := flag.Bool("reverse", false, "write the sentences in reverse order, last sentence first, after all filtering; -combined, -bilingual and -numeric-out are reversed as well")
+	stripBoilerplate := flag.Bool("strip-boilerplate", false, "remove lines that appear in more than -boilerplate-threshold of the input files, such as repeated headers and footers, reporting each one")
+	boilerplateThreshold := flag.Float64("boilerplate-threshold", 0.5, "with -strip-boilerplate, the fraction of files a line must exceed to be stripped")
+	maxBytes := flag.Int64("max-output-bytes", 0, "largest size in bytes of each sentence, side (-single-out, -pairs, -rejected, ...) and -charset output file, encoded and headers included; larger outputs fail and are removed unless -chunk-on-overflow is set; the reports -distribution, -error-report, -metrics, -checksums and -write-manifest are not limited (0 = no limit)")
+	overflowChunks := flag.Bool("chunk-on-overflow", false, "with -max-output-bytes, split an output that would be larger into numbered files holding as many sentences as fit, at most -chunk each when set, instead of failing")
+	chunkSize := flag.Int("chunk", 0, "split each sentence output (per-file, -combined, -bilingual, -numeric-out) into files of at most this many sentences, numbered like out_sc.0001.txt (0 = one file)")
+	incremental := flag.Bool("incremental", false, "skip input files unchanged (same size and modification time) since the run recorded in -state whose outputs still exist; any other flag change reprocesses everything (not with table formats or outputs gathered from all inputs, such as -combined, -bilingual or -charset)")
+	statePath := flag.String("state", ".sentencer-state.json", "with -incremental, the file recording the processed input files")
+	skipEmpty := flag.Bool("skip-empty", false, "do not create output files that would contain no sentences")
+	numericPath := flag.String("numeric-out", "", "write the sentences dominated by a number, with digits and no more letters than digits such as 3.5kg or 10%, to this file")
+	numbersPath := flag.String("extract-numbers", "", "write every date and number found in the sentences as value<TAB>file:line<TAB>sentence rows to this file")
+	rejectedPath := flag.String("rejected", "", "write the sentences dropped by -grep, -collapse-adjacent, -dedup, -exclude-existing, -max-punct-ratio, -require-balanced, -lang-hint, -target and -strip-boilerplate, tagged with the filter, to this file")
+	bilingualPath := flag.String("bilingual", "", "write the sentences containing both Chinese and English to this file")
+	alignedPath := flag.String("aligned", "", "write the Chinese and English fragments of each input line as aligned chinese<TAB>english TSV columns to this file, leaving a column empty when a line has fewer fragments of that language")
+	segmentPath := flag.String("segment", "", "write every Chinese sentence, cut into space-separated words, to this file (needs -tags segment)")
+	pairsPath := flag.String("pairs", "", "write every two consecutive sentences of one input line as a sentenceA<TAB>sentenceB row to this file, for next-sentence-prediction datasets")
+	changedPath := flag.String("changed-only", "", "write every sentence modified by cleaning after splitting (-digit-form, -canon-punct, ...) as an original<TAB>cleaned row to this file")
+	charsetPath := flag.String("charset", "", "write every unique Han character of the Chinese sentences, most frequent first, to this file")
+	startIndexPath := flag.String("start-index", "", "write every distinct first Han character of the Chinese sentences, after any opening quotes, with its count, to this file in code point order")
+	charsetCounts := flag.Bool("charset-counts", true, "include each character's count in the -charset file")
+	failOnEmpty := flag.Bool("fail-on-empty-output", false, "exit with status 1 when no sentence was extracted from any input")
+	checksumsPath := flag.String("checksums", "", "write the SHA-256 of every output file to this file in sha256sum format")
+	errorReportPath := flag.String("error-report", "", "write every input file that failed, with the stage (read or write) and error, as JSON to this file")
+	metricsPath := flag.String("metrics", "", "write the processing metrics (sentencer_inputs_total, sentencer_errors_total, sentencer_lines_total, sentencer_sentences_total by lang, sentencer_processing_seconds_total) in Prometheus text format to this file")
+	manifestPath := flag.String("write-manifest", "", "after a successful run, write a JSON list of the output files with their sizes, line counts and SHA-256 to this file")
+	timeout := flag.Duration("timeout", 0, "stop after this long, skipping files not yet read, writing the sentences collected so far and exiting with status 3 (0 = no limit)")
+	writeBuffer := flag.Int("write-buffer", 64*1024, "capacity in bytes of the buffered writer of each output file")
+	fileMode := flag.String("file-mode", "", "octal permissions up to 0777 of every output file, e.g. 0664, applied regardless of the umask (default: 0644 less the umask for new files)")
+	writeRetries := flag.Int("write-retries", 2, "retry an output file this many times after a transient write error, with a doubling backoff from 100ms")
+	flag.Parse()
+
+	opts := sentencer.DefaultOptions()
+	opts.SplitEnum = *splitEnum
+	opts.KeepUnits = *keepUnits
+	opts.ScriptSplit = *scriptSplit
+	opts.EnglishTokenizer = *englishTokenizer
+	opts.CollapsePunct = *collapsePunct
+	opts.SplitTableGlyphs = !*tableGlyphs
+	opts.WholeFile = *wholeFile
+	opts.KeepDividers = *keepDividers
+	opts.ReportEmptyLines = *warnEmpty
+	switch *trim {
+	case sentencer.TrimBoth, sentencer.TrimRight, sentencer.TrimNone:
+		opts.Trim = *trim
+	default:
+		fmt.Printf("Unknown -trim mode %q (expected both, right or none)\n", *trim)
+		return 2
+	}
+	switch *colon {
+	case sentencer.ColonHard, sentencer.ColonSoft, sentencer.ColonNone:
+		opts.Colon = *colon
+	default:
+		fmt.Printf("Unknown -colon mode %q (expected hard, soft or none)\n", *colon)
+		return 2
+	}
+	switch *combinedMode {
+	case sentencer.CombinedModeLines, sentencer.CombinedModeBlocks:
+		opts.CombinedMode = *combinedMode
+	default:
+		fmt.Printf("Unknown -combined-mode %q (expected lines or blocks)\n", *combinedMode)
+		return 2
+	}
+	if *combinedMode == sentencer.CombinedModeBlocks && *combinedSep != "" {
+		fmt.Println("-combined-sep joins fragments of different languages and cannot be used with -combined-mode blocks")
+		return 2
+	}
+	opts.NoZhSemicolon = !*zhSemicolon
+	opts.EnSemicolon = *enSemicolon
+	switch *overlap {
+	case sentencer.OverlapBoth, sentencer.OverlapSplit, sentencer.OverlapDrop:
+		opts.Overlap = *overlap
+	default:
+		fmt.Printf("Unknown -overlap policy %q (expected both, split or drop)\n", *overlap)
+		return 2
+	}
+	switch *target {
+	case "", sentencer.TargetChinese, sentencer.TargetEnglish:
+		opts.Target = *target
+	default:
+		fmt.Printf("Unknown -target language %q (expected zh or en)\n", *target)
+		return 2
+	}
+	switch *langHint {
+	case sentencer.LangHintAuto, sentencer.LangHintChinese, sentencer.LangHintEnglish:
+		opts.LangHint = *langHint
+	default:
+		fmt.Printf("Unknown -lang-hint %q (expected zh, en or auto)\n", *langHint)
+		return 2
+	}
+	switch *digits {
+	case sentencer.DigitsNeutral, sentencer.DigitsChinese, sentencer.DigitsEnglish:
+		opts.Digits = *digits
+	default:
+		fmt.Printf("Unknown -digits policy %q (expected neutral, chinese or english)\n", *digits)
+		return 2
+	}
+	switch *alnumTokens {
+	case sentencer.AlnumKeep, sentencer.AlnumSplit:
+		opts.AlnumTokens = *alnumTokens
+	default:
+		fmt.Printf("Unknown -alnum-tokens mode %q (expected keep or split)\n", *alnumTokens)
+		return 2
+	}
+	opts.MaxFragments = *maxFragments
+	opts.Workers = *workers
+	opts.Open = inputOpener{client: &http.Client{Timeout: *urlTimeout}, maxBytes: *urlMaxBytes}.open
+	opts.CanonPunct = *canonPunct
+	opts.CanonPunctEnglish = *canonPunctEnglish
+	opts.DespaceCJK = *despaceCJK
+	opts.IdeographicSpace = *ideographicSpace
+	opts.StripDialogue = *stripDialogue
+	opts.StripInvisible = *stripInvisible
+	opts.StraightQuotes = *straightQuotes
+	opts.UnifyDashes = *unifyDashes
+	switch *digitForm {
+	case "", sentencer.DigitFormASCII, sentencer.DigitFormFullWidth, sentencer.DigitFormHanzi:
+		opts.DigitForm = *digitForm
+	default:
+		fmt.Printf("Unknown -digit-form %q (expected ascii, fullwidth or hanzi)\n", *digitForm)
+		return 2
+	}
+	opts.CollapseAdjacent = *collapseAdjacent
+	if *dedupNormalized {
+		opts.Dedup = sentencer.DedupNormalized
+	} else if *dedup || *dedupWindow > 0 {
+		opts.Dedup = sentencer.DedupExact
+	}
+	if *dedupWindow < 0 {
+		fmt.Println("-dedup-window must not be negative")
+		return 2
+	}
+	opts.DedupWindow = *dedupWindow
+	if *dedupCache < 0 {
+		fmt.Println("-dedup-cache must not be negative")
+		return 2
+	}
+	opts.DedupCache = *dedupCache
+	if *terminators != "" {
+		if err := sentencer.ValidateTerminators(*terminators); err != nil {
+			fmt.Println("Invalid -terminators:", err)
+			return 2
+		}
+		opts.Terminators = *terminators
+	}
+	if *stripRanges {
+		if err := sentencer.ValidateRangePairs(*stripRangePairs); err != nil {
+			fmt.Println("Invalid -strip-range-pairs:", err)
+			return 2
+		}
+		opts.StripRanges = *stripRangePairs
+	}
+	if *grepPattern != "" {
+		grepRegex, err := regexp.Compile(*grepPattern)
+		if err != nil {
+			fmt.Println("Invalid -grep pattern:", err)
+			return 2
+		}
+		opts.Grep = grepRegex
+		opts.GrepInvert = *grepInvert
+	}
+	if *blocklistPath != "" {
+		blocklist, err := loadBlocklist(*blocklistPath)
+		if err != nil {
+			fmt.Println("Invalid -blocklist:", err)
+			return 2
+		}
+		opts.Blocklist = blocklist
+	}
+	if *excludeExisting != "" {
+		baseline, err := loadBaseline(*excludeExisting)
+		if err != nil {
+			fmt.Println("Error reading -exclude-existing baseline:", err)
+			return 2
+		}
+		opts.Exclude = baseline
+	}
+	if *maxPunctRatio < 0 || *maxPunctRatio > 1 {
+		fmt.Println("-max-punct-ratio must be between 0 and 1")
+		return 2
+	}
+	opts.MaxPunctRatio = *maxPunctRatio
+	opts.RequireBalanced = *requireBalanced
+	opts.Reverse = *reverse
+	opts.KeepRejected = *rejectedPath != ""
+	opts.KeepOriginal = *changedPath != ""
+
+	// -format lists the formats to write, file formats (one output per input, or the -combined file)
+	// and table formats (one file for all inputs) alike
+	tablePaths := map[string]string{formatSQLite: *dbPath, formatParquet: *parquetPath}
+	var fileFormats, tableNames []string
+	seenFormats := map[string]bool{}
+	for _, format := range strings.Split(*outputFormat, ",") {
+		format = strings.TrimSpace(format)
+		if seenFormats[format] {
+			fmt.Printf("Output format %q is listed more than once in -format\n", format)
+			return 2
+		}
+		seenFormats[format] = true
+		if table, ok := tableFormats[format]; ok {
+			switch {
+			case table.write == nil:
+				fmt.Printf("This binary was built without %s support; rebuild it with -tags %s to use -format %s\n", table.name, table.tag, format)
+				return 2
+			case tablePaths[format] == "":
+				fmt.Printf("-format %s requires -%s FILE\n", format, table.flag)
+				return 2
+			}
+			tableNames = append(tableNames, format)
+			continue
+		}
+		if _, ok := formatExtensions[format]; !ok {
+			fmt.Printf("Unknown output format %q (expected text, json, ndjson, sqlite or parquet, or a comma-separated list of them)\n", format)
+			return 2
+		}
+		fileFormats = append(fileFormats, format)
+	}
+	toTable := len(tableNames) > 0
+	if len(fileFormats) == 0 && *combinedPath != "" {
+		fmt.Printf("-format %s already writes all inputs into one file and cannot be used with -combined alone; add a file format such as text\n", *outputFormat)
+		return 2
+	}
+	combinedPaths := map[string]string{}
+	if *combinedPath != "" {
+		for i, format := range fileFormats {
+			combinedPaths[format] = combinedPathFor(*combinedPath, format, i == 0)
+			if i > 0 && combinedPaths[format] == *combinedPath {
+				fmt.Printf("-combined %s cannot hold both %s and %s output; give it the extension of the first format\n", *combinedPath, fileFormats[0], format)
+				return 2
+			}
+		}
+	}
+	if *writeBuffer <= 0 {
+		fmt.Println("-write-buffer must be positive")
+		return 2
+	}
+	if *fromLine < 0 || *toLine < 0 || (*toLine > 0 && *fromLine > *toLine) {
+		fmt.Println("-from-line and -to-line must not be negative, and -from-line must not exceed -to-line")
+		return 2
+	}
+	opts.FromLine, opts.ToLine = *fromLine, *toLine
+	var segmentWords func(text string) []string
+	if *segmentPath != "" {
+		if newSegmenter == nil {
+			fmt.Println("This binary was built without word segmentation support; rebuild it with -tags segment to use -segment")
+			return 2
+		}
+		var err error
+		if segmentWords, err = newSegmenter(); err != nil {
+			fmt.Println("Error loading the segmentation dictionary:", err)
+			return 1
+		}
+	}
+	if *incremental && toTable {
+		fmt.Println("-incremental needs one output per input; it cannot be used with -format sqlite or -format parquet")
+		return 2
+	}
+	if aggregates := aggregateFlagsSet(); *incremental && len(aggregates) > 0 {
+		fmt.Printf("-incremental leaves unchanged inputs out of outputs gathered from all inputs; it cannot be used with %s\n", strings.Join(aggregates, ", "))
+		return 2
+	}
+	if *urlTimeout <= 0 || *urlMaxBytes <= 0 {
+		fmt.Println("-url-timeout and -url-max-bytes must be positive")
+		return 2
+	}
+	if *serveMaxBytes <= 0 {
+		fmt.Println("-serve-max-bytes must be positive")
+		return 2
+	}
+	if *chunkSize < 0 {
+		fmt.Println("-chunk must not be negative")
+		return 2
+	}
+	if *maxBytes < 0 {
+		fmt.Println("-max-output-bytes must not be negative")
+		return 2
+	}
+	if *overflowChunks && *maxBytes == 0 {
+		fmt.Println("-chunk-on-overflow needs -max-output-bytes")
+		return 2
+	}
+	maxOutputBytes, chunkOnOverflow = *maxBytes, *overflowChunks
+	if _, err := newOutputEncoder(*outputEncoding); err != nil {
+		fmt.Println("Error:", err)
+		return 2
+	}
+	if *fileMode != "" {
+		mode, err := parseFileMode(*fileMode)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return 2
+		}
+		outputFileMode = mode
+	}
+	opts.CombinedSep = unescapeSeparator(*combinedSep)
+	opts.InlineTags = *inlineTags
+	outOpts := outputOptions{format: formatText, encoding: *outputEncoding, retries: *writeRetries, buffer: *writeBuffer, withEndType: *withEndType, withCounts: *withCounts, withID: *withID, withComplexity: *complexity, withMatcher: *withMatcher, jsonIndent: *jsonIndent, splitOpts: opts}
+
+	if *checkConfig {
+		fmt.Println("Configuration is valid.")
+		return 0
+	}
+	if *selfTest {
+		if !runSelfTest() {
+			return 1
+		}
+		return 0
+	}
+
+	if *serveAddr != "" {
+		if err := serve(*serveAddr, opts, *serveMaxBytes); err != nil {
+			fmt.Println("Error serving:", err)
+			return 1
+		}
+		return 0
+	}
+
+	// Step 1: Take the input files from the command line, or let the user select one with sqweek/dialog
+	inputFilePaths := flag.Args()
+	if len(inputFilePaths) == 0 {
+		inputFilePath, err := dialog.File().
+			Filter("Text Files", "txt").
+			Title("Select Input File").
+			Load()
+		if err != nil {
+			if err == dialog.Cancelled {
+				fmt.Println("File selection was cancelled.")
+			} else {
+				fmt.Println("Error selecting input file:", err)
+				return 1
+			}
+			return 0
 		}
-		return
+		inputFilePaths = []string{inputFilePath}
 	}
 
-	// Display selected input file path
-	fmt.Println("Selected input file:", inputFilePath)
+	outOpts.jsonGrouped = *combinedPath != "" && len(inputFilePaths) > 1 && !*jsonFlat
 
-	// Step 2: Construct output file path by appending the suffix '_sc' to the input file base name
-	fileDir := filepath.Dir(inputFilePath)
-	fileName := strings.TrimSuffix(filepath.Base(inputFilePath), filepath.Ext(inputFilePath))
-	outputFilePath := filepath.Join(fileDir, fileName+"_sc"+filepath.Ext(inputFilePath))
+	wholeFileSet := false
+	flag.Visit(func(f *flag.Flag) { wholeFileSet = wholeFileSet || f.Name == "whole-file" })
+	if !wholeFileSet && allPDF(inputFilePaths) {
+		fmt.Println("All inputs are PDF files; enabling -whole-file to join their wrapped lines.")
+		opts.WholeFile = true
+	}
 
-	// Step 3: Open the input file for reading
-	inputFileContent, err := os.ReadFile(inputFilePath)
-	if err != nil {
-		fmt.Println("Error reading input file:", err)
-		return
+	if *showDiff > 0 {
+		if err := printDiffs(inputFilePaths, *showDiff, opts); err != nil {
+			fmt.Println("Error reading input file:", err)
+			return 1
+		}
+		return 0
+	}
+
+	if *debugMatches > 0 {
+		if err := printMatches(inputFilePaths, *debugMatches, opts); err != nil {
+			fmt.Println("Error reading input file:", err)
+			return 1
+		}
+		return 0
+	}
+
+	var state *incrementalState
+	var unchangedOutputs []string // Outputs of the skipped inputs, still current
+	if *incremental {
+		var err error
+		if state, err = loadState(*statePath, runConfig()); err != nil {
+			fmt.Println("Error reading state file:", err)
+			return 1
+		}
+		var changed []string
+		for _, path := range inputFilePaths {
+			if state.unchanged(path) {
+				fmt.Println("Unchanged since the last run; skipping input file:", path)
+				unchangedOutputs = append(unchangedOutputs, state.outputs(path)...)
+				continue
+			}
+			changed = append(changed, path)
+		}
+		inputFilePaths = changed
+	}
+
+	// Step 2: Read the input files and split their content after Chinese punctuation, several files at a time
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	exitCode := 0
+	timedOut := false
+	extracted := 0                      // Sentences extracted from all inputs
+	outputOwners := map[string]string{} // Output path -> input file that produced it
+	var written manifest
+	var failures errorReport
+	recordOutput := func(path string) {
+		if err := written.add(path); err != nil {
+			fmt.Println("Error recording output file in manifest:", err)
+			exitCode = 1
+		}
+	}
+	// The outputs of skipped inputs belong to the run's manifest and checksums as much as new ones
+	for _, path := range unchangedOutputs {
+		recordOutput(path)
+	}
+	// recordState notes in the -incremental state that the input was fully processed into outputs
+	recordState := func(inputPath string, fileResult sentencer.FileResult, outputs []string) {
+		if state == nil || fileResult.Partial {
+			return
+		}
+		if err := state.record(inputPath, outputs); err != nil {
+			fmt.Println("Error recording input file in state:", err)
+			exitCode = 1
+		}
+	}
+	// formatOpts returns the output options writing the given file format
+	formatOpts := func(format string) outputOptions {
+		o := outOpts
+		o.format = format
+		return o
 	}
+	var groups []fileSentences
+	// writeBuckets writes the sentences of each length bucket next to outputPath for -bucket-files
+	writeBuckets := func(outputPath string, groups []fileSentences, groupByFile bool, format string) error {
+		for i, b := range bucketGroups(groups) {
+			path := bucketPath(outputPath, i)
+			if err := writeCombinedOutput(path, b, groupByFile, formatOpts(format)); err != nil {
+				return err
+			}
+			recordOutput(path)
+		}
+		return nil
+	}
+	var distribution charDistribution
+	charset := hanCounter{}
+	starts := hanCounter{}
+	var bilingual, numeric, segmented []string
+	var aligned, numbers, single, pairs, changed []string
+	var rejected []string
+	var metrics processingMetrics
+	handle := func(fileResult sentencer.FileResult) {
+		inputFilePath := fileResult.Path
 
-	// Step 4: Define regex to match Chinese punctuation marks
-	punctuationRegex := regexp.MustCompile(`([，。？：！；、……——])`) // Captures Chinese punctuation marks
+		// Display selected input file path
+		fmt.Println("Selected input file:", inputFilePath)
+		switch {
+		case errors.Is(fileResult.Err, context.DeadlineExceeded) && (!fileResult.Partial || fileResult.Result.Stats.Lines == 0):
+			// Writing nothing would overwrite the output of an earlier run that got further
+			fmt.Println("Warning: -timeout expired before any line of the file was read; skipping it.")
+			timedOut = true
+			return
+		case errors.Is(fileResult.Err, context.DeadlineExceeded):
+			fmt.Printf("Warning: -timeout expired after %d lines; keeping the sentences collected so far.\n", fileResult.Result.Stats.Lines)
+			timedOut = true
+		case errors.Is(fileResult.Err, sentencer.ErrEmptyFile):
+			fmt.Println("Warning: input file is empty; nothing to process.")
+			return
+		case errors.Is(fileResult.Err, sentencer.ErrBinaryFile):
+			fmt.Println("Error: input file looks binary (many NUL bytes), not a text file:", inputFilePath)
+			failures.add(inputFilePath, stageRead, fileResult.Err)
+			exitCode = 1
+			return
+		case fileResult.Err != nil:
+			fmt.Println("Error reading input file:", fileResult.Err)
+			failures.add(inputFilePath, stageRead, fileResult.Err)
+			exitCode = 1
+			return
+		}
 
-	// Step 5: Process the entire file content to insert a newline after each punctuation mark
-	processedContent := punctuationRegex.ReplaceAllString(string(inputFileContent), "$1\n") // Replace punctuation with itself followed by a newline (actual newline)
+		timings := fileResult.Result.Stats.Timings
+		metrics.add(fileResult.Result.Stats, timings.Read+timings.Split+timings.Clean)
 
-	// Step 6: Remove empty lines from the processed content
-	var cleanedLines []string
-	scanner := bufio.NewScanner(strings.NewReader(processedContent))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text()) // Trim whitespace around each line
-		if line != "" {                           // Exclude empty lines
-			cleanedLines = append(cleanedLines, line)
+		// Step 3: Report lines whose fragments were capped or that produced nothing
+		fragments := "fragments"
+		if opts.MaxFragments == 1 {
+			fragments = "fragment"
+		}
+		for _, line := range fileResult.Result.Stats.CappedLines {
+			fmt.Printf("Warning: line %d has more than %d %s; keeping the rest of the line as one fragment\n", line, opts.MaxFragments, fragments)
+		}
+		for _, empty := range fileResult.Result.Stats.EmptyLines {
+			fmt.Printf("Warning: line %d produced no sentences: %s\n", empty.Line, empty.Snippet)
+		}
+		sentences := fileResult.Result.Sentences
+		extracted += len(sentences)
+		if *bucket {
+			fmt.Println("Length buckets:", bucketCounts(bucketGroups([]fileSentences{{path: inputFilePath, sentences: sentences}})))
+		}
+		distribution.add(sentences)
+		charset.add(fileResult.Result.Chinese)
+		starts.addStarts(fileResult.Result.Chinese)
+		for _, r := range fileResult.Result.Rejected {
+			rejected = append(rejected, r.Filter+"\t"+r.Text)
 		}
+		if *alignedPath != "" {
+			aligned = append(aligned, alignedLines(sentencer.AlignedRows(sentences, opts))...)
+		}
+		if *changedPath != "" {
+			changed = append(changed, changedLines(sentences)...)
+		}
+		if *pairsPath != "" {
+			pairs = gatherStream(pairs, pairLines(sentences, *reverse), *reverse)
+		}
+		if *singleOutPath != "" {
+			texts := make([]string, len(sentences))
+			for i, s := range sentences {
+				texts[i] = s.Text
+			}
+			single = gatherStream(single, texts, *reverse)
+		}
+		if *numbersPath != "" {
+			numbers = append(numbers, numberLines(inputFilePath, sentences)...)
+		}
+		bilingual = gatherStream(bilingual, fileResult.Result.Bilingual, *reverse)
+		numeric = gatherStream(numeric, fileResult.Result.Numeric, *reverse)
+		if segmentWords != nil {
+			segmented = gatherStream(segmented, segmentLines(fileResult.Result.Chinese, segmentWords), *reverse)
+		}
+
+		// Sentences for the combined output or a table format are written once all inputs are processed
+		if *combinedPath != "" || toTable {
+			groups = append(groups, fileSentences{path: inputFilePath, sentences: sentences})
+		}
+		if *combinedPath != "" || len(fileFormats) == 0 {
+			if *verbose {
+				printStats(fileResult.Result.Stats, 0)
+			}
+			return
+		}
+
+		// Step 4: Write cleaned sentences to one output file per selected format
+		var writeTime time.Duration
+		var written []string
+		failed := false
+		for _, format := range fileFormats {
+			ext := formatExtensions[format]
+			if ext == "" && len(fileFormats) > 1 {
+				ext = ".txt" // The input's extension, such as .json, could be that of another format
+			}
+			outputFilePath := outputPathFor(inputFilePath, ext, *outDir, *preservePaths)
+			if previous, ok := outputOwners[outputFilePath]; ok {
+				hint := "use -preserve-paths"
+				if *outDir == "" {
+					hint = "rename one of the inputs"
+				}
+				fmt.Printf("Error: output %s of %s would overwrite the output of %s; %s\n", outputFilePath, inputFilePath, previous, hint)
+				failures.add(inputFilePath, stageWrite, fmt.Errorf("output %s would overwrite the output of %s", outputFilePath, previous))
+				failed = true
+				continue
+			}
+			outputOwners[outputFilePath] = inputFilePath
+			if *skipEmpty && len(sentences) == 0 {
+				fmt.Printf("No sentences extracted; skipping output file: %s\n", outputFilePath)
+				continue
+			}
+			if *outDir != "" {
+				if err := os.MkdirAll(filepath.Dir(outputFilePath), 0755); err != nil {
+					fmt.Println("Error creating output directory:", err)
+					failures.add(inputFilePath, stageWrite, err)
+					failed = true
+					continue
+				}
+			}
+			writeStart := time.Now()
+			outputPaths, err := writeChunks(outputFilePath, len(sentences), *chunkSize, formatOpts(format), func(w io.Writer, start, end int) error {
+				return writeSentences(w, sentences[start:end], formatOpts(format))
+			})
+			if err != nil {
+				fmt.Println("Error writing to output file:", err)
+				failures.add(inputFilePath, stageWrite, err)
+				failed = true
+				continue
+			}
+			writeTime += time.Since(writeStart)
+			for _, path := range outputPaths {
+				recordOutput(path)
+			}
+			written = append(written, outputPaths...)
+			if *bucketFiles {
+				if err := writeBuckets(outputFilePath, []fileSentences{{path: inputFilePath, sentences: sentences}}, false, format); err != nil {
+					fmt.Println("Error writing length bucket file:", err)
+					failures.add(inputFilePath, stageWrite, err)
+					failed = true
+					continue
+				}
+			}
+
+			// Notify the user of successful processing
+			fmt.Printf("Processed file with empty lines removed has been saved to: %s\n", describePaths(outputPaths))
+		}
+		if failed {
+			exitCode = 1
+			return
+		}
+		if *verbose {
+			printStats(fileResult.Result.Stats, writeTime)
+		}
+		recordState(inputFilePath, fileResult, written)
 	}
-	if err := scanner.Err(); err != nil {
-		fmt.Println("Error while removing empty lines:", err)
-		return
+	var processErr error
+	if *stripBoilerplate {
+		// Boilerplate is found across all inputs, so they are all held before any is written
+		var fileResults []sentencer.FileResult
+		processErr = sentencer.ProcessFilesFunc(ctx, inputFilePaths, opts, func(fileResult sentencer.FileResult) error {
+			fileResults = append(fileResults, fileResult)
+			return nil
+		})
+		for _, b := range sentencer.StripBoilerplate(fileResults, *boilerplateThreshold, opts) {
+			fmt.Printf("Stripped boilerplate line found in %d files: %s\n", b.Files, b.Line)
+		}
+		for _, fileResult := range fileResults {
+			handle(fileResult)
+		}
+	} else {
+		// Each input is written out as soon as it is split, so only -workers inputs are held at a time
+		processErr = sentencer.ProcessFilesFunc(ctx, inputFilePaths, opts, func(fileResult sentencer.FileResult) error {
+			handle(fileResult)
+			return nil
+		})
+	}
+	if processErr != nil {
+		fmt.Println("Error processing input files:", processErr)
+		return 1
 	}
 
-	// Combine cleaned lines into the final output
-	cleanedContent := strings.Join(cleanedLines, "\n")
+	metrics.addErrors(len(failures.Errors))
+	if *errorReportPath != "" {
+		if err := failures.write(*errorReportPath); err != nil {
+			fmt.Println("Error writing error report:", err)
+			return 1
+		}
+		fmt.Printf("Error report of %d failed file(s) has been saved to: %s\n", len(failures.Errors), *errorReportPath)
+		recordOutput(*errorReportPath)
+	}
 
-	// Step 7: Write cleaned content to the output file
-	err = os.WriteFile(outputFilePath, []byte(cleanedContent), 0644)
-	if err != nil {
-		fmt.Println("Error writing to output file:", err)
-		return
+	// Each file's sentences are already reversed; reversing the files completes the order across inputs
+	if *reverse {
+		for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+			groups[i], groups[j] = groups[j], groups[i]
+		}
 	}
 
-	// Notify the user of successful processing
-	fmt.Printf("Processed file with empty lines removed has been saved to: %s\n", outputFilePath)
+	if *combinedPath != "" && *skipEmpty && countSentences(groups) == 0 {
+		fmt.Printf("No sentences extracted; skipping combined output file: %s\n", *combinedPath)
+	} else if *combinedPath != "" {
+		for _, format := range fileFormats {
+			path := combinedPaths[format]
+			chunkPaths, err := writeChunks(path, countSentences(groups), *chunkSize, formatOpts(format), func(w io.Writer, start, end int) error {
+				return writeCombined(w, sliceGroups(groups, start, end), *groupByFile, formatOpts(format))
+			})
+			if err != nil {
+				fmt.Println("Error writing to combined output file:", err)
+				return 1
+			}
+			fmt.Printf("Combined output of %d file(s) has been saved to: %s\n", len(groups), describePaths(chunkPaths))
+			for _, path := range chunkPaths {
+				recordOutput(path)
+			}
+			if *bucketFiles {
+				if err := writeBuckets(path, groups, *groupByFile, format); err != nil {
+					fmt.Println("Error writing length bucket file:", err)
+					return 1
+				}
+			}
+		}
+	}
+
+	for _, format := range tableNames {
+		table, path := tableFormats[format], tablePaths[format]
+		if err := table.write(path, groups, opts); err != nil {
+			fmt.Printf("Error writing %s output: %v\n", table.name, err)
+			return 1
+		}
+		fmt.Printf("%d sentence(s) of %d file(s) have been saved to the %s file: %s\n", countSentences(groups), len(groups), table.name, path)
+		recordOutput(path)
+	}
+
+	if *distributionPath != "" {
+		if err := writeDistribution(*distributionPath, distribution); err != nil {
+			fmt.Println("Error writing distribution file:", err)
+			return 1
+		}
+		fmt.Printf("Character distribution has been saved to: %s\n", *distributionPath)
+		recordOutput(*distributionPath)
+	}
+
+	if *bilingualPath != "" && *skipEmpty && len(bilingual) == 0 {
+		fmt.Printf("No bilingual sentences extracted; skipping bilingual file: %s\n", *bilingualPath)
+	} else if *bilingualPath != "" {
+		bilingualPaths, err := writeChunks(*bilingualPath, len(bilingual), *chunkSize, outOpts, func(w io.Writer, start, end int) error {
+			return writeRecords(w, bilingual[start:end], "\n")
+		})
+		if err != nil {
+			fmt.Println("Error writing bilingual file:", err)
+			return 1
+		}
+		fmt.Printf("%d bilingual sentence(s) have been saved to: %s\n", len(bilingual), describePaths(bilingualPaths))
+		for _, path := range bilingualPaths {
+			recordOutput(path)
+		}
+	}
+
+	if *numericPath != "" && *skipEmpty && len(numeric) == 0 {
+		fmt.Printf("No numeric sentences extracted; skipping numeric file: %s\n", *numericPath)
+	} else if *numericPath != "" {
+		numericPaths, err := writeChunks(*numericPath, len(numeric), *chunkSize, outOpts, func(w io.Writer, start, end int) error {
+			return writeRecords(w, numeric[start:end], "\n")
+		})
+		if err != nil {
+			fmt.Println("Error writing numeric file:", err)
+			return 1
+		}
+		fmt.Printf("%d numeric sentence(s) have been saved to: %s\n", len(numeric), describePaths(numericPaths))
+		for _, path := range numericPaths {
+			recordOutput(path)
+		}
+	}
+
+	if *segmentPath != "" && *skipEmpty && len(segmented) == 0 {
+		fmt.Printf("No Chinese sentences extracted; skipping segmented file: %s\n", *segmentPath)
+	} else if *segmentPath != "" {
+		segmentPaths, err := writeChunks(*segmentPath, len(segmented), *chunkSize, outOpts, func(w io.Writer, start, end int) error {
+			return writeRecords(w, segmented[start:end], "\n")
+		})
+		if err != nil {
+			fmt.Println("Error writing segmented file:", err)
+			return 1
+		}
+		fmt.Printf("%d segmented Chinese sentence(s) have been saved to: %s\n", len(segmented), describePaths(segmentPaths))
+		for _, path := range segmentPaths {
+			recordOutput(path)
+		}
+	}
+
+	if *rejectedPath != "" {
+		rejectedPaths, err := writeLines(*rejectedPath, rejected, outOpts)
+		if err != nil {
+			fmt.Println("Error writing rejected file:", err)
+			return 1
+		}
+		fmt.Printf("%d rejected sentence(s) have been saved to: %s\n", len(rejected), describePaths(rejectedPaths))
+		for _, path := range rejectedPaths {
+			recordOutput(path)
+		}
+	}
+
+	if *alignedPath != "" {
+		alignedPaths, err := writeLines(*alignedPath, aligned, outOpts)
+		if err != nil {
+			fmt.Println("Error writing aligned file:", err)
+			return 1
+		}
+		fmt.Printf("%d aligned row(s) have been saved to: %s\n", len(aligned), describePaths(alignedPaths))
+		for _, path := range alignedPaths {
+			recordOutput(path)
+		}
+	}
+
+	if *singleOutPath != "" {
+		singlePaths, err := writeJoined(*singleOutPath, single, unescapeSeparator(*recordJoin), outOpts)
+		if err != nil {
+			fmt.Println("Error writing single output file:", err)
+			return 1
+		}
+		fmt.Printf("%d sentence(s) have been saved to: %s\n", len(single), describePaths(singlePaths))
+		for _, path := range singlePaths {
+			recordOutput(path)
+		}
+	}
+
+	if *numbersPath != "" {
+		numbersPaths, err := writeLines(*numbersPath, numbers, outOpts)
+		if err != nil {
+			fmt.Println("Error writing numbers file:", err)
+			return 1
+		}
+		fmt.Printf("%d number(s) and date(s) have been saved to: %s\n", len(numbers), describePaths(numbersPaths))
+		for _, path := range numbersPaths {
+			recordOutput(path)
+		}
+	}
+
+	if *pairsPath != "" {
+		pairsPaths, err := writeLines(*pairsPath, pairs, outOpts)
+		if err != nil {
+			fmt.Println("Error writing pairs file:", err)
+			return 1
+		}
+		fmt.Printf("%d sentence pair(s) have been saved to: %s\n", len(pairs), describePaths(pairsPaths))
+		for _, path := range pairsPaths {
+			recordOutput(path)
+		}
+	}
+
+	if *changedPath != "" {
+		changedPaths, err := writeLines(*changedPath, changed, outOpts)
+		if err != nil {
+			fmt.Println("Error writing changed file:", err)
+			return 1
+		}
+		fmt.Printf("%d changed sentence(s) have been saved to: %s\n", len(changed), describePaths(changedPaths))
+		for _, path := range changedPaths {
+			recordOutput(path)
+		}
+	}
+
+	if *charsetPath != "" {
+		charsetPaths, err := writeCharset(*charsetPath, charset, *charsetCounts, outOpts)
+		if err != nil {
+			fmt.Println("Error writing charset file:", err)
+			return 1
+		}
+		fmt.Printf("Han character set of %d characters has been saved to: %s\n", len(charset), describePaths(charsetPaths))
+		for _, path := range charsetPaths {
+			recordOutput(path)
+		}
+	}
+
+	if *startIndexPath != "" {
+		startIndexPaths, err := writeLines(*startIndexPath, starts.indexLines(), outOpts)
+		if err != nil {
+			fmt.Println("Error writing start index file:", err)
+			return 1
+		}
+		fmt.Printf("Start index of %d characters has been saved to: %s\n", len(starts), describePaths(startIndexPaths))
+		for _, path := range startIndexPaths {
+			recordOutput(path)
+		}
+	}
+
+	if *metricsPath != "" {
+		if err := writeMetrics(*metricsPath, &metrics); err != nil {
+			fmt.Println("Error writing metrics file:", err)
+			return 1
+		}
+		fmt.Println("Metrics have been saved to:", *metricsPath)
+		recordOutput(*metricsPath)
+	}
+
+	if *checksumsPath != "" {
+		if err := written.writeChecksums(*checksumsPath); err != nil {
+			fmt.Println("Error writing checksums file:", err)
+			return 1
+		}
+		fmt.Printf("Checksums of %d output file(s) have been saved to: %s\n", len(written.Files), *checksumsPath)
+	}
+
+	if state != nil {
+		if err := state.save(*statePath); err != nil {
+			fmt.Println("Error writing state file:", err)
+			return 1
+		}
+	}
+
+	if *failOnEmpty && extracted == 0 {
+		fmt.Println("Error: no sentences were extracted from any input.")
+		if exitCode == 0 {
+			exitCode = 1
+		}
+	}
+	if timedOut {
+		exitCode = exitTimeout
+	}
+	if *manifestPath != "" {
+		if exitCode != 0 {
+			fmt.Println("Some files failed or were cut short; not writing the manifest.")
+			return exitCode
+		}
+		if err := written.write(*manifestPath); err != nil {
+			fmt.Println("Error writing manifest file:", err)
+			return 1
+		}
+		fmt.Printf("Manifest of %d output file(s) has been saved to: %s\n", len(written.Files), *manifestPath)
+	}
+	return exitCode
+}
+
+// outputPathFor constructs the output file path by appending the suffix '_sc' to the input file base name.
+// An empty extension keeps the input file's extension, except that PDF inputs get ".txt". With an output directory the file is placed there,
+// under the input's relative directory when preservePaths is set.
+func outputPathFor(inputFilePath, outputExt, outDir string, preservePaths bool) string {
+	name, fileDir := inputFilePath, filepath.Dir(inputFilePath)
+	if isURL(inputFilePath) {
+		name, fileDir = urlBaseName(inputFilePath), "." // Outputs of URLs go to the current directory
+	}
+	if outDir != "" {
+		if preservePaths {
+			fileDir = filepath.Join(outDir, mirroredDir(fileDir))
+		} else {
+			fileDir = outDir
+		}
+	}
+	fileName := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	if outputExt == "" {
+		outputExt = filepath.Ext(name)
+		if isPDF(inputFilePath) || isURL(inputFilePath) {
+			outputExt = ".txt" // The output is the extracted text
+		}
+	}
+	return filepath.Join(fileDir, fileName+"_sc"+outputExt)
+}
+
+// combinedPathFor returns the -combined file of the given format: path itself for the first format
+// listed in -format, and path with the format's extension for the others, so -combined all.txt
+// -format text,json writes all.txt and all.json.
+func combinedPathFor(path, format string, first bool) string {
+	if first {
+		return path
+	}
+	ext := formatExtensions[format]
+	if ext == "" {
+		ext = ".txt"
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ext
+}
+
+// mirroredDir turns an input directory into a relative path that stays inside the output directory,
+// dropping any volume name, leading separator and ".." elements.
+func mirroredDir(dir string) string {
+	dir = filepath.Clean(dir)
+	dir = strings.TrimPrefix(dir, filepath.VolumeName(dir))
+	var parts []string
+	for _, part := range strings.Split(filepath.ToSlash(dir), "/") {
+		if part != "" && part != "." && part != ".." {
+			parts = append(parts, part)
+		}
+	}
+	return filepath.Join(parts...)
+}
+
+// printStats prints the statistics and stage timings of one input file. The write
+// duration is 0 for files that go into the combined output.
+func printStats(stats sentencer.Stats, write time.Duration) {
+	fmt.Printf("Stats: %d lines, %d sentences (%d Chinese, %d English)\n",
+		stats.Lines, stats.Sentences, stats.ChineseSentences, stats.EnglishSentences)
+	fmt.Printf("Timings: read %v, split %v, clean %v, write %v\n",
+		stats.Timings.Read, stats.Timings.Split, stats.Timings.Clean, write)
+}
+
+// printMatches prints where the split pattern matches in the first maxLines lines of each input.
+func printMatches(paths []string, maxLines int, opts sentencer.Options) error {
+	fmt.Println("Split pattern:", opts.SplitPattern())
+	for _, path := range paths {
+		file, err := opts.Open(path)
+		if err != nil {
+			return err
+		}
+		lines, err := sentencer.DebugMatches(file, maxLines, opts)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			fmt.Printf("%s:%d: %s\n", path, line.Line, line.Text)
+			for _, m := range line.Matches {
+				fmt.Printf("  [%d,%d) %q\n", m.Start, m.End, m.Text)
+			}
+		}
+	}
+	return nil
+}
+
+// allPDF reports whether every path names a PDF file.
+func allPDF(paths []string) bool {
+	for _, path := range paths {
+		if !isPDF(path) {
+			return false
+		}
+	}
+	return len(paths) > 0
+}
+
+// gatherStream appends the lines one input contributes to a stream collected from all inputs. With
+// reverse, the input's lines, already reversed, are put before those of the earlier inputs.
+func gatherStream(stream, lines []string, reverse bool) []string {
+	if reverse {
+		return append(append([]string(nil), lines...), stream...)
+	}
+	return append(stream, lines...)
+}
+
+// segmentLines cuts each sentence into words with segmentWords and joins them with spaces.
+func segmentLines(sentences []string, segmentWords func(text string) []string) []string {
+	lines := make([]string, len(sentences))
+	for i, s := range sentences {
+		lines[i] = strings.Join(segmentWords(s), " ")
+	}
+	return lines
+}
+
+// countSentences returns the total number of sentences across all input files.
+func countSentences(groups []fileSentences) int {
+	total := 0
+	for _, g := range groups {
+		total += len(g.sentences)
+	}
+	return total
+}
+
+// processContent runs the pipeline on content and returns the one-per-line text output.
+func processContent(content string, opts sentencer.Options) string {
+	return sentencer.Join(sentencer.ProcessText(content, opts).Sentences)
 }
@@ -0,0 +1,1277 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ljg-cqu/txt-sentencers_cn/sentencer"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// runCommand runs the command with args on a fresh flag set and returns its exit status and
+// everything it printed. The package state run sets is reset first, so tests do not leak flags
+// into each other. args must name at least one input, or a mode such as -selftest, since
+// without inputs the command opens a file dialog.
+func runCommand(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	savedArgs, savedStdout := os.Args, os.Stdout
+	flag.CommandLine = flag.NewFlagSet("sentencer", flag.ContinueOnError)
+	os.Args = append([]string{"sentencer"}, args...)
+	outputFileMode = 0
+	writeRetryBackoff = time.Millisecond
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	printed := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		printed <- string(data)
+	}()
+	defer func() {
+		os.Args, os.Stdout = savedArgs, savedStdout
+	}()
+	code := run()
+	w.Close()
+	return code, <-printed
+}
+
+// chdir changes the working directory to dir for the rest of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// writeFile creates the file name with content, with any missing parent directories.
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFile returns the content of the file name.
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// mustRun runs the command and fails the test unless it exits with want.
+func mustRun(t *testing.T, want int, args ...string) string {
+	t.Helper()
+	code, out := runCommand(t, args...)
+	if code != want {
+		t.Fatalf("%v exited with %d, want %d; output:\n%s", args, code, want, out)
+	}
+	return out
+}
+
+const sampleInput = "你好，世界。今天天气很好！\nHello world. Good day.\n"
+
+func TestSelfTest(t *testing.T) {
+	if out := mustRun(t, 0, "-selftest"); !strings.Contains(out, "Self-test passed.") {
+		t.Errorf("output = %q, want Self-test passed.", out)
+	}
+}
+
+func TestTextOutput(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", sampleInput)
+	mustRun(t, 0, "a.txt")
+	want := "你好，\n世界。\n今天天气很好！\nHello world. Good day."
+	if got := readFile(t, "a_sc.txt"); got != want {
+		t.Errorf("a_sc.txt = %q, want %q", got, want)
+	}
+}
+
+func TestInvalidFlags(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "a.txt")
+	writeFile(t, input, sampleInput)
+	badBlocklist := filepath.Join(dir, "blocklist.txt")
+	writeFile(t, badBlocklist, "# ads\n广告\n(unclosed\n")
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"trim", []string{"-trim", "left", input}},
+		{"colon", []string{"-colon", "maybe", input}},
+		{"overlap", []string{"-overlap", "all", input}},
+		{"digits", []string{"-digits", "roman", input}},
+		{"terminators", []string{"-terminators", "。 ！", input}},
+		{"grep", []string{"-grep", "(", input}},
+		{"blocklist", []string{"-blocklist", badBlocklist, input}},
+		{"format", []string{"-format", "xml", input}},
+		{"format twice", []string{"-format", "text,text", input}},
+		{"sqlite without db", []string{"-format", "sqlite", input}},
+		{"combined table only", []string{"-format", "parquet", "-out", "x.parquet", "-combined", "all.txt", input}},
+		{"encoding", []string{"-output-encoding", "latin1", input}},
+		{"file mode", []string{"-file-mode", "999", input}},
+		{"write buffer", []string{"-write-buffer", "0", input}},
+		{"line range", []string{"-from-line", "5", "-to-line", "2", input}},
+		{"chunk on overflow alone", []string{"-chunk-on-overflow", input}},
+		{"negative chunk", []string{"-chunk", "-1", input}},
+		{"punct ratio", []string{"-max-punct-ratio", "2", input}},
+		{"incremental combined", []string{"-incremental", "-combined", filepath.Join(dir, "all.txt"), input}},
+		{"incremental aggregate", []string{"-incremental", "-bilingual", filepath.Join(dir, "bi.txt"), input}},
+		{"check-config", []string{"-check-config", "-blocklist", badBlocklist}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mustRun(t, 2, tt.args...)
+		})
+	}
+}
+
+func TestCappedLineWarning(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "a.txt")
+	writeFile(t, input, "一，二，三。\n")
+	tests := []struct {
+		max, want string
+	}{
+		{"1", "Warning: line 1 has more than 1 fragment; "},
+		{"2", "Warning: line 1 has more than 2 fragments; "},
+	}
+	for _, tt := range tests {
+		if out := mustRun(t, 0, "-max-sentences-per-line", tt.max, input); !strings.Contains(out, tt.want) {
+			t.Errorf("-max-sentences-per-line %s: output = %q, want %q", tt.max, out, tt.want)
+		}
+	}
+}
+
+func TestInvalidBlocklistLineNumbers(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeFile(t, blocklist, "# ads\n(one\nok\n[two\n")
+	out := mustRun(t, 2, "-check-config", "-blocklist", blocklist)
+	for _, want := range []string{blocklist + ":2:", blocklist + ":4:"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output = %q, want it to report %s", out, want)
+		}
+	}
+}
+
+func TestMissingTableFormatHint(t *testing.T) {
+	if tableFormats[formatParquet].write != nil {
+		t.Skip("built with Parquet support")
+	}
+	input := filepath.Join(t.TempDir(), "a.txt")
+	writeFile(t, input, sampleInput)
+	if out := mustRun(t, 2, "-format", "parquet", "-out", "x.parquet", input); !strings.Contains(out, "-tags parquet,purego") {
+		t.Errorf("output = %q, want the build tags named", out)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	out := mustRun(t, 0, "-check-config", "-format", "text,json", "-grep", "你好")
+	if !strings.Contains(out, "Configuration is valid.") {
+		t.Errorf("output = %q, want Configuration is valid.", out)
+	}
+}
+
+func TestNDJSONOutput(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", sampleInput)
+	mustRun(t, 0, "-format", "ndjson", "a.txt")
+
+	lines := strings.Split(strings.TrimSuffix(readFile(t, "a_sc.ndjson"), "\n"), "\n")
+	want := []record{
+		{SchemaVersion: 1, Text: "你好，", Lang: "zh", SourceLine: 1},
+		{SchemaVersion: 1, Text: "世界。", Lang: "zh", SourceLine: 1},
+		{SchemaVersion: 1, Text: "今天天气很好！", Lang: "zh", SourceLine: 1},
+		{SchemaVersion: 1, Text: "Hello world. Good day.", Lang: "en", SourceLine: 2},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d: %q", len(lines), len(want), lines)
+	}
+	for i, line := range lines {
+		var got record
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("line %d %q: %v", i+1, line, err)
+		}
+		if got != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i+1, got, want[i])
+		}
+	}
+}
+
+func TestJSONIndent(t *testing.T) {
+	tests := []struct {
+		indent    string
+		wantLines int
+		wantStart string
+	}{
+		{"0", 1, `[{"schema_version":1,`},
+		{"2", 0, "[\n  {\n    \"schema_version\": 1,"},
+		{"4", 0, "[\n    {\n        \"schema_version\": 1,"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.indent, func(t *testing.T) {
+			chdir(t, t.TempDir())
+			writeFile(t, "a.txt", sampleInput)
+			mustRun(t, 0, "-format", "json", "-json-indent", tt.indent, "a.txt")
+			got := readFile(t, "a_sc.json")
+			if !strings.HasPrefix(got, tt.wantStart) {
+				t.Errorf("a_sc.json starts %q, want %q", got, tt.wantStart)
+			}
+			if n := strings.Count(got, "\n"); tt.wantLines > 0 && n != tt.wantLines {
+				t.Errorf("a_sc.json has %d lines, want %d", n, tt.wantLines)
+			}
+			var records []record
+			if err := json.Unmarshal([]byte(got), &records); err != nil || len(records) != 4 {
+				t.Errorf("a_sc.json decodes to %d records, %v; want 4", len(records), err)
+			}
+		})
+	}
+}
+
+func TestGBKOutput(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好，世界。\n我爱😀。\n")
+	mustRun(t, 0, "-output-encoding", "gbk", "a.txt")
+
+	encoded := readFile(t, "a_sc.txt")
+	decoded, err := simplifiedchinese.GBK.NewDecoder().String(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "你好，\n世界。\n我爱\x1a。"; decoded != want {
+		t.Errorf("decoded a_sc.txt = %q, want %q", decoded, want)
+	}
+	if want := "\xc4\xe3\xba\xc3"; !strings.HasPrefix(encoded, want) {
+		t.Errorf("a_sc.txt starts % x, want the GBK bytes of 你好 % x", encoded[:4], want)
+	}
+}
+
+func TestCombinedGroupByFile(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好。\n")
+	writeFile(t, "b/b.txt", "Hello.\n再见。\n")
+	mustRun(t, 0, "-combined", "all.txt", "-group-by-file", "a.txt", "b/b.txt")
+
+	want := "# === a.txt ===\n你好。\n# === b.txt ===\nHello.\n再见。"
+	if got := readFile(t, "all.txt"); got != want {
+		t.Errorf("all.txt = %q, want %q", got, want)
+	}
+	if _, err := os.Stat("a_sc.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("-combined also wrote a per-file output: %v", err)
+	}
+}
+
+func TestCombinedJSON(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好。Hello.\n")
+	writeFile(t, "b.txt", "2021\n")
+
+	mustRun(t, 0, "-combined", "all.json", "-format", "json", "a.txt", "b.txt")
+	var groups []fileGroup
+	if err := json.Unmarshal([]byte(readFile(t, "all.json")), &groups); err != nil {
+		t.Fatal(err)
+	}
+	want := []fileGroup{
+		{File: "a.txt", Chinese: []record{{SchemaVersion: 1, Text: "你好。", Lang: "zh", SourceLine: 1}},
+			English: []record{{SchemaVersion: 1, Text: "Hello.", Lang: "en", SourceLine: 1}}},
+		{File: "b.txt", Chinese: []record{}, English: []record{},
+			Other: []record{{SchemaVersion: 1, Text: "2021", SourceLine: 1}}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("all.json = %+v, want %+v", groups, want)
+	}
+
+	mustRun(t, 0, "-combined", "all.json", "-format", "json", "-json-flat", "a.txt", "b.txt")
+	var records []record
+	if err := json.Unmarshal([]byte(readFile(t, "all.json")), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[2].Text != "2021" {
+		t.Errorf("-json-flat all.json = %+v, want the 3 records in input order", records)
+	}
+}
+
+func TestMultipleFormats(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", sampleInput)
+	writeFile(t, "b.txt", "再见。\n")
+
+	mustRun(t, 0, "-format", "text, json,ndjson", "a.txt")
+	for _, name := range []string{"a_sc.txt", "a_sc.json", "a_sc.ndjson"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("-format text,json,ndjson did not write %s: %v", name, err)
+		}
+	}
+
+	mustRun(t, 0, "-combined", "all.txt", "-format", "text,json", "a.txt", "b.txt")
+	if got := readFile(t, "all.txt"); !strings.HasSuffix(got, "\n再见。") {
+		t.Errorf("all.txt = %q, want the text of both inputs", got)
+	}
+	if got := readFile(t, "all.json"); !strings.HasPrefix(got, "[") {
+		t.Errorf("all.json = %q, want a json array", got)
+	}
+
+	mustRun(t, 2, "-combined", "all.ndjson", "-format", "json,ndjson", "a.txt")
+
+	writeFile(t, "c.json", "你好。\n")
+	mustRun(t, 0, "-format", "text,json", "c.json")
+	if got := readFile(t, "c_sc.txt"); got != "你好。" {
+		t.Errorf("c_sc.txt = %q, want the text output", got)
+	}
+	if got := readFile(t, "c_sc.json"); !strings.HasPrefix(got, "[") {
+		t.Errorf("c_sc.json = %q, want a json array", got)
+	}
+}
+
+func TestWithID(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好。\n")
+
+	mustRun(t, 0, "-with-id", "a.txt")
+	if got, want := readFile(t, "a_sc.txt"), "81fddea03ec6aa29\t你好。"; got != want {
+		t.Errorf("a_sc.txt = %q, want %q", got, want)
+	}
+	mustRun(t, 0, "-with-id", "-inline-tags", "a.txt")
+	if got, want := readFile(t, "a_sc.txt"), "81fddea03ec6aa29\t[zh]你好。"; got != want {
+		t.Errorf("-inline-tags a_sc.txt = %q, want %q", got, want)
+	}
+	mustRun(t, 0, "-with-id", "-format", "ndjson", "a.txt")
+	if got := readFile(t, "a_sc.ndjson"); !strings.Contains(got, `"id":"81fddea03ec6aa29"`) {
+		t.Errorf("a_sc.ndjson = %q, want the id field", got)
+	}
+}
+
+func TestSentenceID(t *testing.T) {
+	for _, text := range []string{"你好。", "Hello world.", ""} {
+		sum := sha256.Sum256([]byte(text))
+		if got, want := sentenceID(text), hex.EncodeToString(sum[:])[:16]; got != want {
+			t.Errorf("sentenceID(%q) = %s, want %s", text, got, want)
+		}
+	}
+}
+
+func TestRecordFields(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好吗？Hi!\n")
+	mustRun(t, 0, "-format", "ndjson", "-with-counts", "-with-end-type", "-with-matcher", "-complexity", "a.txt")
+
+	var got []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(readFile(t, "a_sc.ndjson")), "\n") {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fields)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d records, want 2: %v", len(got), got)
+	}
+	// JSON numbers decode as float64
+	want := []map[string]interface{}{
+		{"runes": 4.0, "bytes": 12.0, "end_type": sentencer.EndQuestion, "complexity": 0.0},
+		{"runes": 3.0, "bytes": 3.0, "end_type": sentencer.EndExclamation},
+	}
+	for i := range want {
+		for key, value := range want[i] {
+			if got[i][key] != value {
+				t.Errorf("record %d: %s = %v, want %v", i+1, key, got[i][key], value)
+			}
+		}
+		if got[i]["matcher"] == nil {
+			t.Errorf("record %d has no matcher field: %v", i+1, got[i])
+		}
+	}
+	if _, ok := got[1]["complexity"]; ok {
+		t.Errorf("English record has a complexity score: %v", got[1])
+	}
+}
+
+func TestComplexityScore(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   float64
+		wantOK bool
+	}{
+		{"你好", 0, true},
+		{"亍", 0.5, true},
+		{"龘", 1, true},
+		{"你龘", 0.5, true},
+		{"你亍龘", 0.5, true},
+		{"Hello 你好！", 0, true},
+		{"Hello", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := complexityScore(tt.text); got != tt.want || ok != tt.wantOK {
+			t.Errorf("complexityScore(%q) = %v, %v, want %v, %v", tt.text, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	common, _ := complexityScore("我们今天去学校上课")
+	rare, _ := complexityScore("魑魅魍魉饕餮")
+	if common >= rare {
+		t.Errorf("common characters score %v, not below the rare ones' %v", common, rare)
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	tests := []struct {
+		runes, want int
+	}{
+		{1, 0}, {10, 0}, {11, 1}, {30, 1}, {31, 2}, {60, 2}, {61, 3}, {500, 3},
+	}
+	for _, tt := range tests {
+		if got := bucketOf(strings.Repeat("字", tt.runes)); got != tt.want {
+			t.Errorf("bucketOf(%d runes) = %s, want %s", tt.runes, lengthBuckets[got].name, lengthBuckets[tt.want].name)
+		}
+	}
+
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", strings.Repeat("字", 9)+"。"+strings.Repeat("字", 10)+"。\n")
+	out := mustRun(t, 0, "-bucket", "-bucket-files", "a.txt")
+	if want := "Length buckets: 1-10: 1, 11-30: 1, 31-60: 0, 61+: 0"; !strings.Contains(out, want) {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if got, want := readFile(t, "a_sc.len11-30.txt"), strings.Repeat("字", 10)+"。"; got != want {
+		t.Errorf("a_sc.len11-30.txt = %q, want %q", got, want)
+	}
+	if got := readFile(t, "a_sc.len61+.txt"); got != "" {
+		t.Errorf("a_sc.len61+.txt = %q, want it empty", got)
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好。\nHi there.\n")
+	mustRun(t, 0, "-distribution", "dist.json", "a.txt")
+
+	var report distributionReport
+	if err := json.Unmarshal([]byte(readFile(t, "dist.json")), &report); err != nil {
+		t.Fatal(err)
+	}
+	// 你好 count as Chinese, the spaceless Hithere as English and the marks 。 and . as other
+	if report.SchemaVersion != 1 || report.TotalCharacters != 11 || report.Chinese.Characters != 2 ||
+		report.English.Characters != 7 || report.Other.Characters != 2 {
+		t.Errorf("dist.json = %+v", report)
+	}
+	if sum := report.Chinese.Percent + report.English.Percent + report.Other.Percent; math.Abs(sum-100) > 1e-9 {
+		t.Errorf("percentages sum to %v, want 100", sum)
+	}
+	if empty := (charDistribution{}).report(); empty.Chinese.Percent != 0 || empty.TotalCharacters != 0 {
+		t.Errorf("report of no characters = %+v, want zeros", empty)
+	}
+}
+
+func TestSkipEmpty(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "blank.txt", "\n \n")
+	writeFile(t, "a.txt", "你好。\n")
+
+	out := mustRun(t, 0, "-skip-empty", "blank.txt", "a.txt")
+	if !strings.Contains(out, "No sentences extracted; skipping output file: blank_sc.txt") {
+		t.Errorf("output = %q, want the skipped file reported", out)
+	}
+	if _, err := os.Stat("blank_sc.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("blank_sc.txt was created: %v", err)
+	}
+	if _, err := os.Stat("a_sc.txt"); err != nil {
+		t.Errorf("a_sc.txt was not created: %v", err)
+	}
+
+	mustRun(t, 0, "-skip-empty", "-combined", "all.txt", "blank.txt")
+	if _, err := os.Stat("all.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("empty all.txt was created: %v", err)
+	}
+
+	mustRun(t, 0, "-skip-empty", "-bilingual", "bilingual.txt", "a.txt")
+	if _, err := os.Stat("bilingual.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("empty bilingual.txt was created: %v", err)
+	}
+
+	mustRun(t, 0, "-skip-empty", "-numeric-out", "numeric.txt", "a.txt")
+	if _, err := os.Stat("numeric.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("empty numeric.txt was created: %v", err)
+	}
+}
+
+func TestFailOnEmptyOutput(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "blank.txt", "\n\n")
+	mustRun(t, 0, "blank.txt")
+	out := mustRun(t, 1, "-fail-on-empty-output", "blank.txt")
+	if !strings.Contains(out, "no sentences were extracted") {
+		t.Errorf("output = %q, want the empty output reported", out)
+	}
+	writeFile(t, "a.txt", "你好。\n")
+	mustRun(t, 0, "-fail-on-empty-output", "blank.txt", "a.txt")
+}
+
+func TestCharsetAndStartIndex(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "好人好事。“你好”。Hello.\n")
+	mustRun(t, 0, "-charset", "charset.tsv", "-start-index", "starts.tsv", "a.txt")
+
+	// Most frequent first, ties in code point order
+	if got, want := readFile(t, "charset.tsv"), schemaHeader+"\n好\t3\n事\t1\n人\t1\n你\t1\n"; got != want {
+		t.Errorf("charset.tsv = %q, want %q", got, want)
+	}
+	// The opening quote is skipped; characters are listed in code point order
+	if got, want := readFile(t, "starts.tsv"), schemaHeader+"\n你\t1\n好\t1"; got != want {
+		t.Errorf("starts.tsv = %q, want %q", got, want)
+	}
+
+	mustRun(t, 0, "-charset", "charset.txt", "-charset-counts=false", "a.txt")
+	if got, want := readFile(t, "charset.txt"), "好\n事\n人\n你\n"; got != want {
+		t.Errorf("charset.txt = %q, want %q", got, want)
+	}
+}
+
+func TestManifestAndChecksums(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", sampleInput)
+	mustRun(t, 0, "-format", "text,json", "-write-manifest", "manifest.json", "-checksums", "sums.txt", "a.txt")
+
+	var m manifest
+	if err := json.Unmarshal([]byte(readFile(t, "manifest.json")), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.SchemaVersion != 1 || len(m.Files) != 2 || m.Files[0].Path != "a_sc.txt" || m.Files[1].Path != "a_sc.json" {
+		t.Fatalf("manifest.json = %+v, want a_sc.txt and a_sc.json", m)
+	}
+	var sums strings.Builder
+	for _, f := range m.Files {
+		content := readFile(t, f.Path)
+		sum := sha256.Sum256([]byte(content))
+		if f.Bytes != int64(len(content)) || f.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("manifest entry %+v does not match the file", f)
+		}
+		fmt.Fprintf(&sums, "%s  %s\n", f.SHA256, f.Path)
+	}
+	// The text output has no newline after its last sentence, which still counts as a line
+	if m.Files[0].Lines != 4 {
+		t.Errorf("a_sc.txt has %d lines in the manifest, want 4", m.Files[0].Lines)
+	}
+	if got := readFile(t, "sums.txt"); got != sums.String() {
+		t.Errorf("sums.txt = %q, want %q", got, sums.String())
+	}
+
+	writeFile(t, "bin.txt", "a\x00b\x00c\x00d\x00")
+	os.Remove("manifest.json")
+	mustRun(t, 1, "-write-manifest", "manifest.json", "a.txt", "bin.txt")
+	if _, err := os.Stat("manifest.json"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("manifest.json was written for a failed run: %v", err)
+	}
+}
+
+func TestErrorReport(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", sampleInput)
+	writeFile(t, "bin.txt", "a\x00b\x00c\x00d\x00")
+	mustRun(t, 1, "-error-report", "errors.json", "a.txt", "bin.txt", "missing.txt")
+
+	var report errorReport
+	if err := json.Unmarshal([]byte(readFile(t, "errors.json")), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.SchemaVersion != 1 || len(report.Errors) != 2 {
+		t.Fatalf("errors.json = %+v, want 2 errors", report)
+	}
+	for i, path := range []string{"bin.txt", "missing.txt"} {
+		if e := report.Errors[i]; e.Path != path || e.Stage != stageRead || e.Error == "" {
+			t.Errorf("error %d = %+v, want a read error of %s", i+1, e, path)
+		}
+	}
+
+	mustRun(t, 0, "-error-report", "errors.json", "a.txt")
+	if got := readFile(t, "errors.json"); !strings.Contains(got, `"errors": []`) {
+		t.Errorf("errors.json of a clean run = %q, want an empty list", got)
+	}
+}
+
+func TestPreservePaths(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a/x.txt", "你好。\n")
+	writeFile(t, "b/x.txt", "再见。\n")
+
+	out := mustRun(t, 1, "-outdir", "out", "a/x.txt", "b/x.txt")
+	if !strings.Contains(out, "would overwrite the output of a/x.txt; use -preserve-paths") {
+		t.Errorf("output = %q, want the collision reported", out)
+	}
+	if got := readFile(t, filepath.Join("out", "x_sc.txt")); got != "你好。" {
+		t.Errorf("out/x_sc.txt = %q, want the output of the first input", got)
+	}
+
+	mustRun(t, 0, "-outdir", "out", "-preserve-paths", "a/x.txt", "b/x.txt")
+	for path, want := range map[string]string{"out/a/x_sc.txt": "你好。", "out/b/x_sc.txt": "再见。"} {
+		if got := readFile(t, filepath.FromSlash(path)); got != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMirroredDir(t *testing.T) {
+	tests := []struct {
+		dir, want string
+	}{
+		{"a/b", "a/b"},
+		{"./a", "a"},
+		{"../../a", "a"},
+		{"/abs/dir", "abs/dir"},
+		{".", ""},
+	}
+	for _, tt := range tests {
+		if got := mirroredDir(filepath.FromSlash(tt.dir)); got != filepath.FromSlash(tt.want) {
+			t.Errorf("mirroredDir(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", sampleInput)
+	out := mustRun(t, exitTimeout, "-timeout", "1ns", "a.txt")
+	if !strings.Contains(out, "-timeout expired") {
+		t.Errorf("output = %q, want the timeout reported", out)
+	}
+	if _, err := os.Stat("a_sc.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("a_sc.txt was written though no line was read: %v", err)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "一。二。三。四。五。\n")
+	mustRun(t, 0, "-chunk", "2", "a.txt")
+
+	for name, want := range map[string]string{"a_sc.0001.txt": "一。\n二。", "a_sc.0002.txt": "三。\n四。", "a_sc.0003.txt": "五。"} {
+		if got := readFile(t, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat("a_sc.0004.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("a_sc.0004.txt was written: %v", err)
+	}
+
+	mustRun(t, 0, "-chunk", "5", "-combined", "all.txt", "-bilingual", "bi.txt", "a.txt")
+	if got := readFile(t, "all.0001.txt"); got != "一。\n二。\n三。\n四。\n五。" {
+		t.Errorf("all.0001.txt = %q, want all five sentences", got)
+	}
+	if got := readFile(t, "bi.0001.txt"); got != "" {
+		t.Errorf("bi.0001.txt = %q, want an empty first chunk", got)
+	}
+}
+
+func TestChunkPath(t *testing.T) {
+	tests := []struct {
+		path  string
+		index int
+		want  string
+	}{
+		{"out_sc.txt", 1, "out_sc.0001.txt"},
+		{"dir/all.json", 12, "dir/all.0012.json"},
+		{"noext", 3, "noext.0003"},
+	}
+	for _, tt := range tests {
+		if got := chunkPath(tt.path, tt.index); got != tt.want {
+			t.Errorf("chunkPath(%q, %d) = %q, want %q", tt.path, tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestMaxOutputBytes(t *testing.T) {
+	chdir(t, t.TempDir())
+	input := "一。二。三。四。五。\n"
+	writeFile(t, "a.txt", input)
+
+	out := mustRun(t, 1, "-max-output-bytes", "20", "a.txt")
+	if !strings.Contains(out, "over -max-output-bytes 20") {
+		t.Errorf("output = %q, want the limit reported", out)
+	}
+	if _, err := os.Stat("a_sc.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("a_sc.txt over the limit was kept: %v", err)
+	}
+
+	mustRun(t, 0, "-max-output-bytes", "20", "-chunk-on-overflow", "a.txt")
+	var sentences []string
+	for i := 1; ; i++ {
+		data, err := os.ReadFile(chunkPath("a_sc.txt", i))
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > 20 {
+			t.Errorf("chunk %d is %d bytes, over the limit", i, len(data))
+		}
+		sentences = append(sentences, strings.Split(string(data), "\n")...)
+	}
+	if got, want := strings.Join(sentences, ""), strings.TrimSuffix(input, "\n"); got != want {
+		t.Errorf("chunks hold %q, want %q", got, want)
+	}
+
+	mustRun(t, 0, "-max-output-bytes", "1000", "-chunk-on-overflow", "a.txt")
+	if _, err := os.Stat("a_sc.txt"); err != nil {
+		t.Errorf("output that fits was not written unnumbered: %v", err)
+	}
+
+	mustRun(t, 1, "-max-output-bytes", "2", "-chunk-on-overflow", "a.txt")
+}
+
+func TestMaxOutputBytesSideFiles(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "一。二。\n") // a_sc.txt is 13 bytes
+	join := []string{"-single-out", "s.txt", "-record-join", "<separator>"}
+
+	mustRun(t, 1, append([]string{"-max-output-bytes", "20"}, append(join, "a.txt")...)...)
+	if _, err := os.Stat("s.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("s.txt over the limit was kept: %v", err)
+	}
+	mustRun(t, 0, append([]string{"-max-output-bytes", "20", "-chunk-on-overflow"}, append(join, "a.txt")...)...)
+	for i, want := range []string{"一。", "二。"} {
+		if got := readFile(t, chunkPath("s.txt", i+1)); got != want {
+			t.Errorf("chunk %d of s.txt = %q, want %q", i+1, got, want)
+		}
+	}
+
+	mustRun(t, 1, "-max-output-bytes", "20", "-pairs", "pairs.tsv", "a.txt")
+	if _, err := os.Stat("pairs.tsv"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("pairs.tsv over the limit was kept: %v", err)
+	}
+	mustRun(t, 1, "-max-output-bytes", "20", "-chunk-on-overflow", "-pairs", "pairs.tsv", "a.txt")
+	mustRun(t, 0, "-max-output-bytes", "40", "-pairs", "pairs.tsv", "a.txt")
+	if got, want := readFile(t, "pairs.tsv"), schemaHeader+"\n一。\t二。"; got != want {
+		t.Errorf("pairs.tsv = %q, want %q", got, want)
+	}
+}
+
+func TestRetryWrite(t *testing.T) {
+	tests := []struct {
+		name         string
+		retries      int
+		err          error
+		failures     int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"success", 2, syscall.EIO, 0, 1, false},
+		{"transient once", 2, syscall.EIO, 1, 2, false},
+		{"transient wrapped", 2, &os.PathError{Op: "write", Path: "x", Err: syscall.EAGAIN}, 2, 3, false},
+		{"retries exhausted", 2, syscall.EIO, 5, 3, true},
+		{"no retries", 0, syscall.EIO, 1, 1, true},
+		{"permission denied", 2, &os.PathError{Op: "open", Path: "x", Err: syscall.EACCES}, 1, 1, true},
+		{"other error", 2, errors.New("disk on fire"), 1, 1, true},
+	}
+	writeRetryBackoff = time.Millisecond
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := retryWrite(tt.retries, func() error {
+				attempts++
+				if attempts <= tt.failures {
+					return tt.err
+				}
+				return nil
+			})
+			if attempts != tt.wantAttempts || (err != nil) != tt.wantErr {
+				t.Errorf("%d attempts, error %v; want %d attempts, error %v", attempts, err, tt.wantAttempts, tt.wantErr)
+			}
+		})
+	}
+}
+
+// flakyWriter fails its first write with a transient error.
+type flakyWriter struct {
+	calls *int
+}
+
+func (f flakyWriter) write(w io.Writer) error {
+	*f.calls++
+	if *f.calls == 1 {
+		io.WriteString(w, "partial")
+		return &os.PathError{Op: "write", Path: "out", Err: syscall.EIO}
+	}
+	_, err := io.WriteString(w, "complete")
+	return err
+}
+
+func TestWriteOutputFileRetries(t *testing.T) {
+	writeRetryBackoff = time.Millisecond
+	path := filepath.Join(t.TempDir(), "out.txt")
+	calls := 0
+	opts := outputOptions{format: formatText, encoding: encodingUTF8, retries: 1, buffer: 4096}
+	if err := writeOutputFile(path, opts, flakyWriter{&calls}.write); err != nil {
+		t.Fatal(err)
+	}
+	// The retry rewrites the whole file, so nothing of the failed attempt is left
+	if got := readFile(t, path); got != "complete" || calls != 2 {
+		t.Errorf("out.txt = %q after %d calls, want complete after 2", got, calls)
+	}
+
+	calls = 0
+	opts.retries = 0
+	if err := writeOutputFile(path, opts, flakyWriter{&calls}.write); !errors.Is(err, syscall.EIO) {
+		t.Errorf("writeOutputFile without retries = %v, want EIO", err)
+	}
+}
+
+func TestFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no Unix permission bits")
+	}
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", sampleInput)
+	writeFile(t, "a_sc.txt", "old") // An existing file gets the mode too
+	mustRun(t, 0, "-file-mode", "0640", "-distribution", "dist.json", "a.txt")
+	for _, name := range []string{"a_sc.txt", "dist.json"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0640 {
+			t.Errorf("%s has mode %v, want 0640", name, info.Mode().Perm())
+		}
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"0664", 0664, false},
+		{"640", 0640, false},
+		{"0777", 0777, false},
+		{"1777", 0, true},
+		{"0999", 0, true},
+		{"rw-r--r--", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseFileMode(tt.s)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseFileMode(%q) = %v, %v, want %v, error %v", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestIncremental(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好。\n")
+	writeFile(t, "b.txt", "再见。\n")
+
+	const skipped = "Unchanged since the last run; skipping input file: "
+	if out := mustRun(t, 0, "-incremental", "a.txt", "b.txt"); strings.Contains(out, skipped) {
+		t.Errorf("first run skipped a file: %q", out)
+	}
+	out := mustRun(t, 0, "-incremental", "a.txt", "b.txt")
+	if !strings.Contains(out, skipped+"a.txt") || !strings.Contains(out, skipped+"b.txt") {
+		t.Errorf("second run output = %q, want both files skipped", out)
+	}
+
+	writeFile(t, "a.txt", "你好吗？\n")
+	os.Remove("b_sc.txt")
+	out = mustRun(t, 0, "-incremental", "a.txt", "b.txt")
+	if strings.Contains(out, skipped) {
+		t.Errorf("run after changes = %q, want the changed input and the one missing its output processed", out)
+	}
+	if got := readFile(t, "a_sc.txt"); got != "你好吗？" {
+		t.Errorf("a_sc.txt = %q, want the new content", got)
+	}
+
+	if out := mustRun(t, 0, "-incremental", "-dedup", "a.txt"); strings.Contains(out, skipped) {
+		t.Errorf("run with other flags = %q, want the state ignored", out)
+	}
+}
+
+func TestIncrementalManifest(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好。\n")
+	writeFile(t, "b.txt", "再见。\n")
+	args := []string{"-incremental", "-write-manifest", "manifest.json", "a.txt", "b.txt"}
+	mustRun(t, 0, args...)
+	writeFile(t, "b.txt", "再见吧。\n")
+	mustRun(t, 0, args...)
+
+	var got manifest
+	if err := json.Unmarshal([]byte(readFile(t, "manifest.json")), &got); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range got.Files {
+		paths = append(paths, f.Path)
+	}
+	if want := []string{"a_sc.txt", "b_sc.txt"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("manifest paths = %q, want %q, the skipped input's output included", paths, want)
+	}
+}
+
+func TestExcludeExisting(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "baseline.txt", "你好。\n  Hello.  \n\n")
+	writeFile(t, "a.txt", "你好。再见。Hello.\n")
+	mustRun(t, 0, "-exclude-existing", "baseline.txt", "-rejected", "rejected.tsv", "a.txt")
+
+	if got := readFile(t, "a_sc.txt"); got != "再见。" {
+		t.Errorf("a_sc.txt = %q, want only the new sentence", got)
+	}
+	want := schemaHeader + "\nexisting\t你好。\nexisting\tHello."
+	if got := readFile(t, "rejected.tsv"); got != want {
+		t.Errorf("rejected.tsv = %q, want %q", got, want)
+	}
+	mustRun(t, 2, "-exclude-existing", "missing.txt", "a.txt")
+}
+
+func TestRejected(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好。你好。世界。Hello!\n")
+	mustRun(t, 0, "-collapse-adjacent", "-grep", "[世界你好]", "-rejected", "rejected.tsv", "a.txt")
+
+	want := schemaHeader + "\nadjacent-duplicate\t你好。\ngrep\tHello!"
+	if got := readFile(t, "rejected.tsv"); got != want {
+		t.Errorf("rejected.tsv = %q, want %q", got, want)
+	}
+}
+
+func TestExtractNumbers(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"2021年3月发布了3.14版本", []string{"2021年3月", "3.14"}},
+		{"版本3.14发布", []string{"3.14"}},
+		{"3月15日和2021-03-15", []string{"3月15日", "2021-03-15"}},
+		{"共1,000人，增长10%，温度-7度", []string{"1,000", "10%", "-7"}},
+		{"没有数字", nil},
+	}
+	for _, tt := range tests {
+		if got := numberPattern.FindAllString(tt.text, -1); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("numbers in %q = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "前言\n2021年3月发布了3.14版本。\n")
+	mustRun(t, 0, "-extract-numbers", "numbers.tsv", "a.txt")
+	want := schemaHeader + "\n2021年3月\ta.txt:2\t2021年3月发布了3.14版本。\n3.14\ta.txt:2\t2021年3月发布了3.14版本。"
+	if got := readFile(t, "numbers.tsv"); got != want {
+		t.Errorf("numbers.tsv = %q, want %q", got, want)
+	}
+}
+
+func TestShowDiff(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", sampleInput)
+	out := mustRun(t, 0, "-show-diff", "5", "a.txt")
+	want := "--- a.txt\n+++ a.txt (cleaned)\n@@ -1,2 +1,4 @@\n-你好，世界。今天天气很好！\n+你好，\n+世界。\n+今天天气很好！\n Hello world. Good day.\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("output = %q, want the diff %q", out, want)
+	}
+	if _, err := os.Stat("a_sc.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("-show-diff wrote an output: %v", err)
+	}
+
+	writeFile(t, "clean.txt", "你好。\n")
+	if out := mustRun(t, 0, "-show-diff", "5", "clean.txt"); strings.Contains(out, "---") {
+		t.Errorf("output for unchanged input = %q, want no diff", out)
+	}
+}
+
+func TestSingleOut(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好。再见。\n")
+	writeFile(t, "b.txt", "Hello.\n")
+
+	tests := []struct {
+		join, want string
+	}{
+		{`\n`, "你好。\n再见。\nHello."},
+		{`\x1e`, "你好。\x1e再见。\x1eHello."},
+		{" | ", "你好。 | 再见。 | Hello."},
+	}
+	for _, tt := range tests {
+		mustRun(t, 0, "-single-out", "all.txt", "-record-join", tt.join, "a.txt", "b.txt")
+		if got := readFile(t, "all.txt"); got != tt.want {
+			t.Errorf("-record-join %s: all.txt = %q, want %q", tt.join, got, tt.want)
+		}
+	}
+	mustRun(t, 0, "-single-out", "all.txt", "-reverse", "a.txt", "b.txt")
+	if got, want := readFile(t, "all.txt"), "Hello.\n再见。\n你好。"; got != want {
+		t.Errorf("-reverse all.txt = %q, want %q", got, want)
+	}
+}
+
+func TestSideFileSchemaHeaders(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好,世界。Hello world!共3个。\n")
+	files := []string{"rejected.tsv", "aligned.tsv", "pairs.tsv", "changed.tsv", "numbers.tsv", "starts.tsv", "charset.tsv"}
+	mustRun(t, 0, "-canon-punct", "-rejected", files[0], "-aligned", files[1], "-pairs", files[2], "-changed-only", files[3],
+		"-extract-numbers", files[4], "-start-index", files[5], "-charset", files[6], "a.txt")
+	for _, name := range files {
+		if got := readFile(t, name); !strings.HasPrefix(got, schemaHeader+"\n") && got != schemaHeader {
+			t.Errorf("%s = %q, want it headed by %q", name, got, schemaHeader)
+		}
+	}
+	if schemaHeader != "# schema_version: 1" {
+		t.Errorf("schemaHeader = %q", schemaHeader)
+	}
+}
+
+func TestPairs(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "甲。乙\t丙。丁。\n戊。\n")
+	mustRun(t, 0, "-pairs", "pairs.tsv", "a.txt")
+	// No pair crosses from line 1 to line 2
+	want := schemaHeader + "\n甲。\t乙 丙。\n乙 丙。\t丁。"
+	if got := readFile(t, "pairs.tsv"); got != want {
+		t.Errorf("pairs.tsv = %q, want %q", got, want)
+	}
+
+	mustRun(t, 0, "-pairs", "pairs.tsv", "-reverse", "a.txt")
+	want = schemaHeader + "\n乙 丙。\t丁。\n甲。\t乙 丙。"
+	if got := readFile(t, "pairs.tsv"); got != want {
+		t.Errorf("-reverse pairs.tsv = %q, want %q", got, want)
+	}
+}
+
+func TestChangedOnly(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好,世界。真的吗?\n不变。\n")
+	mustRun(t, 0, "-canon-punct", "-changed-only", "changed.tsv", "a.txt")
+	want := schemaHeader + "\n你好,世界。\t你好，世界。\n真的吗?\t真的吗？"
+	if got := readFile(t, "changed.tsv"); got != want {
+		t.Errorf("changed.tsv = %q, want %q", got, want)
+	}
+}
+
+func TestAligned(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好。Hello.\n再见。\n")
+	mustRun(t, 0, "-aligned", "aligned.tsv", "a.txt")
+	want := schemaHeader + "\n你好。\tHello.\n再见。\t"
+	if got := readFile(t, "aligned.tsv"); got != want {
+		t.Errorf("aligned.tsv = %q, want %q", got, want)
+	}
+}
+
+func TestMetricsFile(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", sampleInput)
+	writeFile(t, "bin.txt", "a\x00b\x00c\x00d\x00")
+	mustRun(t, 1, "-metrics", "metrics.prom", "a.txt", "bin.txt")
+
+	metrics := readFile(t, "metrics.prom")
+	for _, want := range []string{
+		"sentencer_inputs_total 1\n",
+		"sentencer_errors_total 1\n",
+		"sentencer_lines_total 2\n",
+		`sentencer_sentences_total{lang="zh"} 3` + "\n",
+		`sentencer_sentences_total{lang="en"} 1` + "\n",
+		`sentencer_sentences_total{lang="other"} 0` + "\n",
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("metrics.prom is missing %q:\n%s", want, metrics)
+		}
+	}
+	checkExposition(t, metrics)
+}
+
+// checkExposition fails the test unless every metric of text is preceded by its HELP and TYPE
+// lines, as the Prometheus text format requires.
+func checkExposition(t *testing.T, text string) {
+	t.Helper()
+	typed := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "# TYPE "):
+			if len(fields) != 4 || fields[3] != "counter" {
+				t.Errorf("bad TYPE line %q", line)
+			}
+			typed[fields[2]] = true
+		case strings.HasPrefix(line, "# HELP "):
+		default:
+			name := strings.SplitN(fields[0], "{", 2)[0]
+			if len(fields) != 2 || !typed[name] {
+				t.Errorf("sample %q has no TYPE line or a bad value", line)
+			}
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	server := httptest.NewServer(newServeHandler(sentencer.DefaultOptions(), 64))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/process", "text/plain", strings.NewReader("你好，世界。Hello."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got serveResponse
+	err = json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := serveResponse{SchemaVersion: 1, Combined: []string{"你好，", "世界。", "Hello."},
+		Chinese: []string{"你好，", "世界。"}, English: []string{"Hello."}, Lines: 1, Sentences: 3}
+	if resp.StatusCode != http.StatusOK || !reflect.DeepEqual(got, want) {
+		t.Errorf("POST /process = %d %+v, want 200 %+v", resp.StatusCode, got, want)
+	}
+
+	resp, err = http.Post(server.URL+"/process", "text/plain", strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `"combined":[]`) {
+		t.Errorf("POST /process of no text = %s, want empty streams as []", body)
+	}
+
+	tests := []struct {
+		method, path string
+		body         string
+		wantStatus   int
+	}{
+		{http.MethodPost, "/process", strings.Repeat("字", 30), http.StatusRequestEntityTooLarge},
+		{http.MethodGet, "/process", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/healthz", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.wantStatus)
+		}
+	}
+
+	resp, err = http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/plain; version=0.0.4" {
+		t.Errorf("/metrics Content-Type = %q", ct)
+	}
+	for _, want := range []string{"sentencer_inputs_total 2\n", "sentencer_errors_total 2\n", `sentencer_sentences_total{lang="zh"} 2` + "\n"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("/metrics is missing %q:\n%s", want, body)
+		}
+	}
+	checkExposition(t, string(body))
+}
+
+func TestURLInput(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/docs/page.html", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "你好。Hello.\n")
+	})
+	mux.HandleFunc("/big.txt", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("很长的文本。", 100))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	chdir(t, t.TempDir())
+	mustRun(t, 0, "-outdir", "out", server.URL+"/docs/page.html")
+	if got := readFile(t, filepath.Join("out", "page_sc.txt")); got != "你好。\nHello." {
+		t.Errorf("out/page_sc.txt = %q", got)
+	}
+
+	out := mustRun(t, 1, "-url-max-bytes", "100", server.URL+"/big.txt")
+	if !strings.Contains(out, "exceeds -url-max-bytes") {
+		t.Errorf("output = %q, want the size limit reported", out)
+	}
+	out = mustRun(t, 1, server.URL+"/missing.txt")
+	if !strings.Contains(out, "404") {
+		t.Errorf("output = %q, want the 404 reported", out)
+	}
+}
+
+func TestURLBaseName(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"https://example.com/docs/page.html", "page.html"},
+		{"https://example.com/", "example.com"},
+		{"http://example.com", "example.com"},
+		{"https://example.com/a/b/?q=1", "b"},
+	}
+	for _, tt := range tests {
+		if got := urlBaseName(tt.url); got != tt.want {
+			t.Errorf("urlBaseName(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	var b bytes.Buffer
+	writeUnifiedDiff(&b, "a", "b", []string{"1", "2", "3"}, []string{"1", "x", "3", "4"})
+	want := "--- a\n+++ b\n@@ -1,3 +1,4 @@\n 1\n-2\n+x\n 3\n+4\n"
+	if b.String() != want {
+		t.Errorf("diff = %q, want %q", b.String(), want)
+	}
+}
+
+func BenchmarkWriteBuffer(b *testing.B) {
+	lines := make([]string, 100000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("这是第%d个用于测试写入缓冲区大小的句子。", i)
+	}
+	path := filepath.Join(b.TempDir(), "out.txt")
+	for _, size := range []int{4 << 10, 64 << 10, 1 << 20} {
+		b.Run(fmt.Sprintf("%dKB", size>>10), func(b *testing.B) {
+			opts := outputOptions{format: formatText, encoding: encodingUTF8, buffer: size}
+			for i := 0; i < b.N; i++ {
+				err := writeOutputFile(path, opts, func(w io.Writer) error {
+					for _, line := range lines {
+						if _, err := io.WriteString(w, line+"\n"); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
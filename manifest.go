@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// manifestEntry describes one output file written by the run.
+type manifestEntry struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+	Lines int    `json:"lines"`
+
+	SHA256 string `json:"sha256"` // Hex-encoded SHA-256 of the file's content
+}
+
+// manifest collects the output files written by the run, in the order they were written.
+type manifest struct {
+	SchemaVersion int             `json:"schema_version"`
+	Files         []manifestEntry `json:"files"`
+}
+
+// add records path with its current size, line count and checksum.
+func (m *manifest) add(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entry := manifestEntry{Path: path}
+	hash := sha256.New()
+	buf := make([]byte, 64*1024)
+	var last byte
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			hash.Write(buf[:n])
+			entry.Bytes += int64(n)
+			entry.Lines += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// A final line without a trailing newline still counts
+	if entry.Bytes > 0 && last != '\n' {
+		entry.Lines++
+	}
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	m.Files = append(m.Files, entry)
+	return nil
+}
+
+// write saves the manifest as indented JSON to path.
+func (m *manifest) write(path string) error {
+	if m.Files == nil {
+		m.Files = []manifestEntry{}
+	}
+	m.SchemaVersion = schemaVersion
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutputData(path, append(data, '\n'))
+}
+
+// writeChecksums saves the checksum of every recorded file to path in the format of sha256sum,
+// so "sha256sum -c" can verify that another run produced identical outputs.
+func (m *manifest) writeChecksums(path string) error {
+	var b strings.Builder
+	for _, f := range m.Files {
+		fmt.Fprintf(&b, "%s  %s\n", f.SHA256, f.Path)
+	}
+	return writeOutputData(path, []byte(b.String()))
+}
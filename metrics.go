@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ljg-cqu/txt-sentencers_cn/sentencer"
+)
+
+// processingMetrics accumulates the figures of -metrics and of the /metrics endpoint of -serve. It
+// is safe for concurrent use by the handlers of -serve.
+type processingMetrics struct {
+	mu       sync.Mutex
+	inputs   int // Files, or /process requests, split
+	errors   int // Failures reading or writing files, as in -error-report, or requests refused
+	lines    int
+	chinese  int
+	english  int
+	other    int
+	duration time.Duration
+}
+
+// add counts one input split with the given stats in elapsed time.
+func (m *processingMetrics) add(stats sentencer.Stats, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inputs++
+	m.lines += stats.Lines
+	m.chinese += stats.ChineseSentences
+	m.english += stats.EnglishSentences
+	if other := stats.Sentences - stats.ChineseSentences - stats.EnglishSentences; other > 0 {
+		m.other += other // -overlap drop routes both pieces of a mixed sentence, so this can be negative
+	}
+	m.duration += elapsed
+}
+
+// addErrors counts n failures.
+func (m *processingMetrics) addErrors(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors += n
+}
+
+// write encodes the metrics in the Prometheus text exposition format, version 0.0.4.
+func (m *processingMetrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b bytes.Buffer
+	counter := func(name, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	}
+	counter("sentencer_inputs_total", "Inputs split: files, or /process requests with -serve.")
+	fmt.Fprintf(&b, "sentencer_inputs_total %d\n", m.inputs)
+	counter("sentencer_errors_total", "Failures reading inputs or writing outputs, or refused /process requests.")
+	fmt.Fprintf(&b, "sentencer_errors_total %d\n", m.errors)
+	counter("sentencer_lines_total", "Input lines read.")
+	fmt.Fprintf(&b, "sentencer_lines_total %d\n", m.lines)
+	counter("sentencer_sentences_total", "Sentences kept after cleaning, by language.")
+	fmt.Fprintf(&b, "sentencer_sentences_total{lang=\"zh\"} %d\n", m.chinese)
+	fmt.Fprintf(&b, "sentencer_sentences_total{lang=\"en\"} %d\n", m.english)
+	fmt.Fprintf(&b, "sentencer_sentences_total{lang=\"other\"} %d\n", m.other)
+	counter("sentencer_processing_seconds_total", "Time spent reading, splitting and cleaning the inputs.")
+	fmt.Fprintf(&b, "sentencer_processing_seconds_total %g\n", m.duration.Seconds())
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeMetrics writes the metrics to path, e.g. for the textfile collector of node_exporter.
+func writeMetrics(path string, m *processingMetrics) error {
+	var b bytes.Buffer
+	if err := m.write(&b); err != nil {
+		return err
+	}
+	return writeOutputData(path, b.Bytes())
+}
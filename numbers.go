@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ljg-cqu/txt-sentencers_cn/sentencer"
+)
+
+// numberPattern matches the dates and numeric literals -extract-numbers pulls out of the sentences.
+// Dates come first so 2021年3月 or 2021-03-15 is one match rather than several numbers.
+var numberPattern = regexp.MustCompile(
+	`\d{4}年(?:\d{1,2}月(?:\d{1,2}[日号])?)?` + // 2021年, 2021年3月, 2021年3月15日
+		`|\d{1,2}月\d{1,2}[日号]` + // 3月15日
+		`|\d{4}[-/.]\d{1,2}[-/.]\d{1,2}` + // 2021-03-15, 2021/3/15
+		`|[-+]?\d+(?:,\d{3})*(?:\.\d+)?%?`) // 42, -7, 1,000, 3.14, 10%
+
+// numberLines returns a "match<TAB>path:line<TAB>sentence" line for every date or number found in
+// the sentences of the input at path, in order, so each value keeps its source context.
+func numberLines(path string, sentences []sentencer.Sentence) []string {
+	var lines []string
+	for _, s := range sentences {
+		context := strings.ReplaceAll(s.Text, "\t", " ")
+		for _, match := range numberPattern.FindAllString(s.Text, -1) {
+			lines = append(lines, fmt.Sprintf("%s\t%s:%d\t%s", match, path, s.SourceLine, context))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ljg-cqu/txt-sentencers_cn/sentencer"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+// Supported output formats.
+const (
+	formatText    = "text"
+	formatJSON    = "json"
+	formatNDJSON  = "ndjson"
+	formatSQLite  = "sqlite"  // One database for all inputs; see tableFormats
+	formatParquet = "parquet" // One Parquet file for all inputs; see tableFormats
+)
+
+// formatExtensions maps each output format to the extension of its output file.
+// An empty extension means the input file's extension is kept.
+var formatExtensions = map[string]string{
+	formatText:   "",
+	formatJSON:   ".json",
+	formatNDJSON: ".ndjson",
+}
+
+// tableFormat is an output format holding the sentences of all input files in one table, with
+// the columns lang, text, source_file and source_line. Its write function is only set in builds
+// with the format's build tag (see sqlite.go and parquet.go), which keeps heavy dependencies out
+// of the default binary.
+type tableFormat struct {
+	name  string // Human-readable name of the format
+	tag   string // Build tags enabling the format
+	flag  string // Flag naming the output file
+	write func(path string, groups []fileSentences, opts sentencer.Options) error
+}
+
+// tableFormats are the table output formats by -format name.
+var tableFormats = map[string]*tableFormat{
+	formatSQLite:  {name: "SQLite", tag: "sqlite", flag: "db"},
+	formatParquet: {name: "Parquet", tag: "parquet,purego", flag: "out"}, // segmentio/parquet-go needs purego to link on Go 1.23+
+}
+
+// languageCode returns the lang column value of a sentence classified as sc: "zh", "en" or empty
+// for sentences of neither script.
+func languageCode(sc sentencer.Script) string {
+	switch sc {
+	case sentencer.ScriptChinese:
+		return "zh"
+	case sentencer.ScriptEnglish:
+		return "en"
+	default:
+		return ""
+	}
+}
+
+// Supported output encodings.
+const (
+	encodingUTF8 = "utf-8"
+	encodingGBK  = "gbk"
+)
+
+// outputOptions controls how sentences are written to an output file.
+type outputOptions struct {
+	format   string // One of the format* constants
+	encoding string // One of the encoding* constants
+	retries  int    // Extra attempts after a transient write failure
+	buffer   int    // Capacity in bytes of the buffered writer of each output file
+
+	withEndType    bool              // Add the terminal punctuation category to structured records
+	withCounts     bool              // Add the rune and UTF-8 byte counts to structured records
+	withID         bool              // Add the sentenceID to structured records and text lines
+	withComplexity bool              // Add the complexityScore of Chinese sentences to structured records
+	withMatcher    bool              // Add the splitting stage that ended each sentence to structured records
+	jsonGrouped    bool              // Write combined json output as one object per input file; see fileGroup
+	jsonIndent     int               // Spaces per indentation level of the json format; 0 is compact
+	splitOpts      sentencer.Options // Options of the run, which also shape the text stream
+}
+
+// schemaVersion identifies the structure of the json/ndjson records, the -serve response, the TSV
+// side files and the JSON reports (-distribution, -write-manifest, -error-report), so consumers can
+// adapt across releases. Bump it whenever a field or column is renamed, removed or changes meaning;
+// adding a field, such as lang on records, does not need a bump.
+const schemaVersion = 1
+
+// schemaHeader is the comment line heading the TSV side files, declaring their schemaVersion.
+var schemaHeader = fmt.Sprintf("# schema_version: %d", schemaVersion)
+
+// record is the JSON/NDJSON representation of one sentence.
+type record struct {
+	SchemaVersion int    `json:"schema_version"`
+	ID            string `json:"id,omitempty"`
+	Text          string `json:"text"`
+	Lang          string `json:"lang"` // languageCode of the text: zh, en or "" for neither script
+	SourceLine    int    `json:"source_line"`
+	EndType       string `json:"end_type,omitempty"`
+	Matcher       string `json:"matcher,omitempty"`
+	Runes         int    `json:"runes,omitempty"`
+	Bytes         int    `json:"bytes,omitempty"`
+
+	Complexity *float64 `json:"complexity,omitempty"` // Set for Chinese sentences only; 0 is a valid score
+}
+
+// newRecord builds the structured record of s with the fields selected in opts.
+func newRecord(s sentencer.Sentence, opts outputOptions) record {
+	r := record{SchemaVersion: schemaVersion, Text: s.Text, Lang: languageCode(opts.splitOpts.Classify(s.Text)), SourceLine: s.SourceLine}
+	if opts.withID {
+		r.ID = sentenceID(s.Text)
+	}
+	if opts.withEndType {
+		r.EndType = sentencer.EndType(s.Text)
+	}
+	if opts.withMatcher {
+		r.Matcher = s.Matcher
+	}
+	if opts.withCounts {
+		r.Runes = utf8.RuneCountInString(s.Text)
+		r.Bytes = len(s.Text)
+	}
+	if opts.withComplexity {
+		if score, ok := complexityScore(s.Text); ok {
+			r.Complexity = &score
+		}
+	}
+	return r
+}
+
+// sentenceID returns the stable ID of a sentence: the first 16 hex digits of the SHA-256 of its UTF-8
+// text, the same on every run and platform.
+func sentenceID(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// textLines returns the lines of the text format for sentences. With opts.withID each line is
+// preceded by the sentenceID of its text, without any inline tags, and a tab; the label lines of
+// sentencer.CombinedModeBlocks get no ID.
+func textLines(sentences []sentencer.Sentence, opts outputOptions) []string {
+	if opts.withID && opts.splitOpts.CombinedMode == sentencer.CombinedModeBlocks {
+		var lines []string
+		for _, block := range opts.splitOpts.Blocks(sentences) {
+			lines = append(lines, sentencer.BlockLabel(block.Script))
+			for _, s := range block.Sentences {
+				text := s.Text
+				if opts.splitOpts.InlineTags {
+					text = sentencer.InlineTag(opts.splitOpts.Classify(text)) + text
+				}
+				lines = append(lines, sentenceID(s.Text)+"\t"+text)
+			}
+		}
+		return lines
+	}
+	lines := sentencer.CombinedLines(sentences, opts.splitOpts)
+	if !opts.withID {
+		return lines
+	}
+	plainOpts := opts.splitOpts
+	plainOpts.InlineTags = false
+	for i, plain := range sentencer.CombinedLines(sentences, plainOpts) {
+		lines[i] = sentenceID(plain) + "\t" + lines[i]
+	}
+	return lines
+}
+
+// newOutputEncoder returns the encoder for the named output encoding, or nil for UTF-8.
+// Characters that GBK cannot represent (e.g. emoji) are replaced with the ASCII
+// substitute character 0x1A instead of failing the whole write.
+func newOutputEncoder(name string) (*encoding.Encoder, error) {
+	switch name {
+	case encodingUTF8:
+		return nil, nil
+	case encodingGBK:
+		return encoding.ReplaceUnsupported(simplifiedchinese.GBK.NewEncoder()), nil
+	default:
+		return nil, fmt.Errorf("unknown output encoding %q (expected utf-8 or gbk)", name)
+	}
+}
+
+// fileSentences holds the sentences extracted from one input file.
+type fileSentences struct {
+	path      string
+	sentences []sentencer.Sentence
+}
+
+// fileGroup is the json representation of the sentences of one input file in a combined output
+// with outputOptions.jsonGrouped. Sentences of neither script, such as bare numbers, go to Other.
+type fileGroup struct {
+	File    string   `json:"file"`
+	Chinese []record `json:"chinese"`
+	English []record `json:"english"`
+	Other   []record `json:"other,omitempty"`
+}
+
+// newFileGroup builds the fileGroup of g, routing each sentence by its script.
+func newFileGroup(g fileSentences, opts outputOptions) fileGroup {
+	group := fileGroup{File: g.path, Chinese: []record{}, English: []record{}}
+	for _, s := range g.sentences {
+		r := newRecord(s, opts)
+		switch opts.splitOpts.Classify(s.Text) {
+		case sentencer.ScriptChinese:
+			group.Chinese = append(group.Chinese, r)
+		case sentencer.ScriptEnglish:
+			group.English = append(group.English, r)
+		default:
+			group.Other = append(group.Other, r)
+		}
+	}
+	return group
+}
+
+// writeCombinedOutput writes the sentences of several input files to one file. With groupByFile,
+// each file's sentences are preceded by a commented header line in the text format; structured
+// formats simply concatenate the records, except json with jsonGrouped, which writes an array of
+// fileGroup objects.
+func writeCombinedOutput(path string, groups []fileSentences, groupByFile bool, opts outputOptions) error {
+	return writeOutputFile(path, opts, func(w io.Writer) error {
+		return writeCombined(w, groups, groupByFile, opts)
+	})
+}
+
+// writeCombined encodes the sentences of several input files to w as writeCombinedOutput does.
+func writeCombined(w io.Writer, groups []fileSentences, groupByFile bool, opts outputOptions) error {
+	if opts.format == formatJSON && opts.jsonGrouped {
+		fileGroups := make([]fileGroup, len(groups))
+		for i, g := range groups {
+			fileGroups[i] = newFileGroup(g, opts)
+		}
+		data, err := marshalJSON(fileGroups, opts.jsonIndent)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+	if opts.format != formatText {
+		all := []sentencer.Sentence{}
+		for _, g := range groups {
+			all = append(all, g.sentences...)
+		}
+		return writeSentences(w, all, opts)
+	}
+
+	var lines []string
+	for _, g := range groups {
+		if groupByFile {
+			lines = append(lines, "# === "+filepath.Base(g.path)+" ===")
+		}
+		lines = append(lines, textLines(g.sentences, opts)...)
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// outputFileMode is the permission bits of the output files, set with -file-mode. Zero keeps
+// defaultFileMode.
+var outputFileMode os.FileMode
+
+// defaultFileMode is the permission bits, less the umask, of output files created without
+// -file-mode. Existing files keep their mode.
+const defaultFileMode os.FileMode = 0644
+
+// createOutputFile creates or truncates the output file at path with outputFileMode.
+func createOutputFile(path string) (*os.File, error) {
+	if outputFileMode == 0 {
+		return os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, defaultFileMode)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, outputFileMode)
+	if err != nil {
+		return nil, err
+	}
+	// OpenFile applies the umask and leaves the mode of an existing file alone
+	if err := file.Chmod(outputFileMode); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// writeOutputData writes data to the output file at path, created with createOutputFile.
+func writeOutputData(path string, data []byte) error {
+	file, err := createOutputFile(path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// parseFileMode parses the octal -file-mode value, such as 0664 or 664, accepting only
+// permission bits.
+func parseFileMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid file mode %q (expected octal permissions such as 0664)", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// writeRetryBackoff is the delay before the first retry; it doubles on every further attempt.
+var writeRetryBackoff = 100 * time.Millisecond
+
+// retryWrite runs op, repeating it up to retries more times while it fails with a retryable error.
+func retryWrite(retries int, op func() error) error {
+	delay := writeRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= retries || !isRetryableWriteError(err) {
+			return err
+		}
+		fmt.Printf("Transient write error, retrying in %v: %v\n", delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// isRetryableWriteError reports whether err looks transient, as seen on networked or FUSE
+// filesystems. Errors such as permission denied or a missing directory fail fast.
+func isRetryableWriteError(err error) bool {
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	for _, errno := range []syscall.Errno{syscall.EAGAIN, syscall.EINTR, syscall.EIO, syscall.EBUSY, syscall.ETIMEDOUT} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeLines writes a side file of plain lines, such as the -pairs rows, under schemaHeader,
+// honoring the output encoding and retries but not the structured formats. It is written like the
+// sentence outputs, so -max-output-bytes applies and -chunk-on-overflow may split it, each chunk
+// repeating the header; the paths written are returned.
+func writeLines(path string, lines []string, opts outputOptions) ([]string, error) {
+	return writeChunks(path, len(lines), 0, opts, func(w io.Writer, start, end int) error {
+		return writeRecords(w, append([]string{schemaHeader}, lines[start:end]...), "\n")
+	})
+}
+
+// writeJoined writes the records to path joined by sep, with no separator after the last one,
+// like writeLines but without a header.
+func writeJoined(path string, records []string, sep string, opts outputOptions) ([]string, error) {
+	return writeChunks(path, len(records), 0, opts, func(w io.Writer, start, end int) error {
+		return writeRecords(w, records[start:end], sep)
+	})
+}
+
+// writeRecords writes the records to w joined by sep, with no separator after the last one.
+func writeRecords(w io.Writer, records []string, sep string) error {
+	_, err := io.WriteString(w, strings.Join(records, sep))
+	return err
+}
+
+// alignedLines formats aligned rows as TSV lines, replacing tabs inside the sentences with spaces
+// so they cannot shift the columns.
+func alignedLines(rows [][2]string) []string {
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = strings.ReplaceAll(row[0], "\t", " ") + "\t" + strings.ReplaceAll(row[1], "\t", " ")
+	}
+	return lines
+}
+
+// pairLines returns a "sentenceA<TAB>sentenceB" line for every two consecutive sentences of one
+// input line, the unit the splitter treats as a paragraph, so no pair crosses a paragraph. With
+// reverse the sentences are in reverse order and each pair is put back in reading order. Tabs
+// inside the sentences are written as spaces.
+func pairLines(sentences []sentencer.Sentence, reverse bool) []string {
+	var lines []string
+	for i := 1; i < len(sentences); i++ {
+		a, b := sentences[i-1], sentences[i]
+		if a.SourceLine != b.SourceLine {
+			continue
+		}
+		if reverse {
+			a, b = b, a
+		}
+		lines = append(lines, strings.ReplaceAll(a.Text, "\t", " ")+"\t"+strings.ReplaceAll(b.Text, "\t", " "))
+	}
+	return lines
+}
+
+// changedLines returns an "original<TAB>cleaned" line for every sentence whose Original differs
+// from its cleaned text. Tabs inside the sentences are written as spaces.
+func changedLines(sentences []sentencer.Sentence) []string {
+	var lines []string
+	for _, s := range sentences {
+		if s.Original != s.Text {
+			lines = append(lines, strings.ReplaceAll(s.Original, "\t", " ")+"\t"+strings.ReplaceAll(s.Text, "\t", " "))
+		}
+	}
+	return lines
+}
+
+// writeOutputFile writes path through retryWrite, so a transient failure rewrites the whole file.
+func writeOutputFile(path string, opts outputOptions, write func(w io.Writer) error) error {
+	return retryWrite(opts.retries, func() error {
+		return writeOutputFileOnce(path, opts, write)
+	})
+}
+
+// writeOutputFileOnce creates path and hands write a buffered writer, transcoding the output
+// when an output encoding other than UTF-8 is selected.
+func writeOutputFileOnce(path string, opts outputOptions, write func(w io.Writer) error) error {
+	encoder, err := newOutputEncoder(opts.encoding)
+	if err != nil {
+		return err
+	}
+
+	file, err := createOutputFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriterSize(file, opts.buffer)
+	var w io.Writer = writer
+	var transcoder io.WriteCloser
+	if encoder != nil {
+		transcoder = transform.NewWriter(writer, encoder)
+		w = transcoder
+	}
+	if err := write(w); err != nil {
+		return err
+	}
+	if transcoder != nil {
+		// Closing the transform writer flushes any runes it is still holding
+		if err := transcoder.Close(); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// unescapeSeparator interprets Go escape sequences such as \t or \x1e in a separator flag,
+// returning the value unchanged when it is not a valid escaped string.
+func unescapeSeparator(sep string) string {
+	if unquoted, err := strconv.Unquote(`"` + sep + `"`); err == nil {
+		return unquoted
+	}
+	return sep
+}
+
+// marshalJSON encodes v compactly when indent is 0 and with indent spaces per level otherwise.
+func marshalJSON(v interface{}, indent int) ([]byte, error) {
+	if indent <= 0 {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", strings.Repeat(" ", indent))
+}
+
+// writeSentences encodes the sentences to w in the selected format.
+func writeSentences(w io.Writer, sentences []sentencer.Sentence, opts outputOptions) error {
+	switch opts.format {
+	case formatText:
+		if !opts.withID {
+			// The library's combined stream is exactly the text format
+			return sentencer.WriteStreams(sentencer.WriterSet{Combined: w}, sentencer.Result{Sentences: sentences}, opts.splitOpts)
+		}
+		return writeRecords(w, textLines(sentences, opts), "\n")
+	case formatJSON:
+		records := make([]record, len(sentences))
+		for i, s := range sentences {
+			records[i] = newRecord(s, opts)
+		}
+		data, err := marshalJSON(records, opts.jsonIndent)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case formatNDJSON:
+		// Encode records one at a time so each line is an independent JSON document
+		encoder := json.NewEncoder(w)
+		for _, s := range sentences {
+			if err := encoder.Encode(newRecord(s, opts)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", opts.format)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build parquet
+
+package main
+
+import (
+	"github.com/ljg-cqu/txt-sentencers_cn/sentencer"
+	"github.com/segmentio/parquet-go"
+)
+
+func init() {
+	tableFormats[formatParquet].write = writeParquetFile
+}
+
+// parquetRow is one sentence in the Parquet output.
+type parquetRow struct {
+	Lang       string `parquet:"lang"`
+	Text       string `parquet:"text"`
+	SourceFile string `parquet:"source_file"`
+	SourceLine int64  `parquet:"source_line"`
+}
+
+// writeParquetFile writes every sentence of groups to a new Parquet file at path.
+func writeParquetFile(path string, groups []fileSentences, opts sentencer.Options) error {
+	file, err := createOutputFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := parquet.NewGenericWriter[parquetRow](file)
+	for _, g := range groups {
+		rows := make([]parquetRow, len(g.sentences))
+		for i, s := range g.sentences {
+			rows[i] = parquetRow{Lang: languageCode(opts.Classify(s.Text)), Text: s.Text, SourceFile: g.path, SourceLine: int64(s.SourceLine)}
+		}
+		if _, err := writer.Write(rows); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
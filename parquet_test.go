@@ -0,0 +1,34 @@
+//go:build parquet
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/parquet-go"
+)
+
+func TestParquetOutput(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好。Hello.\n")
+	writeFile(t, "b.txt", "\n2021\n")
+	mustRun(t, 0, "-format", "text,parquet", "-out", "out.parquet", "a.txt", "b.txt")
+
+	got, err := parquet.ReadFile[parquetRow]("out.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []parquetRow{
+		{Lang: "zh", Text: "你好。", SourceFile: "a.txt", SourceLine: 1},
+		{Lang: "en", Text: "Hello.", SourceFile: "a.txt", SourceLine: 1},
+		{Lang: "", Text: "2021", SourceFile: "b.txt", SourceLine: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %+v, want %+v", got, want)
+	}
+	// The text format is still written per input next to the table
+	if got := readFile(t, "a_sc.txt"); got != "你好。\nHello." {
+		t.Errorf("a_sc.txt = %q", got)
+	}
+}
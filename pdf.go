@@ -0,0 +1,42 @@
+//go:build pdf
+
+package main
+
+import (
+	"io"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+func init() {
+	openPDF = openPDFText
+}
+
+// openPDFText extracts the text of the PDF file at path, one line per row of text on each page.
+func openPDFText(path string) (io.ReadCloser, error) {
+	file, reader, err := pdf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var b strings.Builder
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		rows, err := page.GetTextByRow()
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			for _, text := range row.Content {
+				b.WriteString(text.S)
+			}
+			b.WriteByte('\n')
+		}
+	}
+	return io.NopCloser(strings.NewReader(b.String())), nil
+}
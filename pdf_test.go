@@ -0,0 +1,42 @@
+//go:build pdf
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPDFInput(t *testing.T) {
+	file, err := openPDFText(filepath.Join("testdata", "pdf", "sample.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "Hello world.\nIt rains today.\n"; got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "sample.pdf")
+	pdf, err := os.ReadFile(filepath.Join("testdata", "pdf", "sample.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, input, string(pdf))
+	out := mustRun(t, 0, input)
+	// The rows of a PDF are joined as wrapped lines, and the output gets the .txt extension
+	if !strings.Contains(out, "enabling -whole-file") {
+		t.Errorf("output = %q, want -whole-file enabled", out)
+	}
+	if got := readFile(t, filepath.Join(dir, "sample_sc.txt")); got != "Hello world. It rains today." {
+		t.Errorf("sample_sc.txt = %q", got)
+	}
+}
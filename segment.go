@@ -0,0 +1,31 @@
+//go:build segment
+
+package main
+
+import (
+	"strings"
+
+	"github.com/go-ego/gse"
+)
+
+func init() {
+	newSegmenter = newGSESegmenter
+}
+
+// newGSESegmenter loads the simplified Chinese dictionary embedded in gse and returns a segmenter
+// cutting text with it, using the HMM model for words missing from the dictionary.
+func newGSESegmenter() (func(text string) []string, error) {
+	seg, err := gse.NewEmbed("zh_s")
+	if err != nil {
+		return nil, err
+	}
+	return func(text string) []string {
+		var words []string
+		for _, word := range seg.Cut(text, true) {
+			if word = strings.TrimSpace(word); word != "" {
+				words = append(words, word)
+			}
+		}
+		return words
+	}, nil
+}
@@ -0,0 +1,33 @@
+//go:build segment
+
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSegment(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "我爱北京天安门。Hello world.\n")
+	mustRun(t, 0, "-segment", "words.txt", "a.txt")
+
+	got := readFile(t, "words.txt")
+	if strings.Contains(got, "Hello") {
+		t.Errorf("words.txt = %q, want only the Chinese sentence", got)
+	}
+	if words := strings.Fields(got); len(words) < 3 || strings.Join(words, "") != "我爱北京天安门。" {
+		t.Errorf("words.txt = %q, want 我爱北京天安门。 cut into words", got)
+	}
+}
+
+func TestSegmentSkipEmpty(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "Hello world.\n")
+	mustRun(t, 0, "-skip-empty", "-segment", "words.txt", "a.txt")
+	if _, err := os.Stat("words.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("empty words.txt was created: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+
+	"github.com/ljg-cqu/txt-sentencers_cn/sentencer"
+)
+
+// The self-test fixture and its golden outputs. Each expected file records exactly what the
+// pipeline does to input.txt under one selfTestCase and must be regenerated whenever the output
+// intentionally changes.
+var (
+	//go:embed testdata/selftest/input.txt
+	selfTestInput string
+
+	//go:embed testdata/selftest/*.txt
+	selfTestFiles embed.FS
+)
+
+// selfTestCase runs the fixture with the options set by configure, on top of DefaultOptions,
+// against the golden file testdata/selftest/<golden>.
+type selfTestCase struct {
+	name      string
+	golden    string
+	configure func(o *sentencer.Options)
+}
+
+// selfTestCases covers the default pipeline and the flags that change where sentences end.
+// Flags that only shape the output streams or formats are not covered here.
+var selfTestCases = []selfTestCase{
+	{"default", "expected.txt", func(o *sentencer.Options) {}},
+	{"-script-split", "expected_script_split.txt", func(o *sentencer.Options) { o.ScriptSplit = true }},
+	{"-english-tokenizer", "expected_english_tokenizer.txt", func(o *sentencer.Options) { o.EnglishTokenizer = true }},
+	{"-split-enum=false", "expected_no_split_enum.txt", func(o *sentencer.Options) { o.SplitEnum = false }},
+	{"-colon soft", "expected_colon_soft.txt", func(o *sentencer.Options) { o.Colon = sentencer.ColonSoft }},
+	{"-collapse-punct", "expected_collapse_punct.txt", func(o *sentencer.Options) { o.CollapsePunct = true }},
+	{"-whole-file", "expected_whole_file.txt", func(o *sentencer.Options) { o.WholeFile = true }},
+	{"-trim right", "expected_trim_right.txt", func(o *sentencer.Options) { o.Trim = sentencer.TrimRight }},
+}
+
+// runSelfTest runs the bundled fixture through the full pipeline under every selfTestCase and
+// reports each line that differs from its golden output. It returns true when all of them match.
+func runSelfTest() bool {
+	failed := 0
+	for _, c := range selfTestCases {
+		want, err := selfTestFiles.ReadFile("testdata/selftest/" + c.golden)
+		if err != nil {
+			fmt.Printf("%s: %v\n", c.name, err)
+			failed++
+			continue
+		}
+		opts := sentencer.DefaultOptions()
+		c.configure(&opts)
+		if !compareSelfTest(c.name, processContent(selfTestInput, opts), string(want)) {
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("Self-test failed: %d of %d cases differ from their golden files.\n", failed, len(selfTestCases))
+		return false
+	}
+	fmt.Println("Self-test passed.")
+	return true
+}
+
+// compareSelfTest prints every line where got differs from want under the case name and
+// reports whether they are identical.
+func compareSelfTest(name, got, want string) bool {
+	if got == want {
+		return true
+	}
+	gotLines := strings.Split(got, "\n")
+	wantLines := strings.Split(want, "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var gotLine, wantLine string
+		if i < len(gotLines) {
+			gotLine = gotLines[i]
+		}
+		if i < len(wantLines) {
+			wantLine = wantLines[i]
+		}
+		if gotLine != wantLine {
+			fmt.Printf("%s: line %d:\n  want: %q\n  got:  %q\n", name, i+1, wantLine, gotLine)
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package sentencer
+
+import "sort"
+
+// Boilerplate is a line stripped by StripBoilerplate.
+type Boilerplate struct {
+	Line  string // The line's cleaned fragments joined together
+	Files int    // Number of files the line appeared in
+}
+
+// StripBoilerplate removes repeated headers and footers from a batch: every line appearing in
+// more than threshold (a fraction between 0 and 1) of the successfully processed files is
+// dropped from all of them. Lines are compared by their cleaned fragments. Batches of fewer
+// than two files are left alone. The stripped lines are returned, most widespread first.
+func StripBoilerplate(results []FileResult, threshold float64, opts Options) []Boilerplate {
+	files := 0
+	counts := map[string]int{}
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		files++
+		seen := map[string]bool{}
+		for _, key := range lineKeys(r.Result.Sentences) {
+			if !seen[key] {
+				seen[key] = true
+				counts[key]++
+			}
+		}
+	}
+	if files < 2 {
+		return nil
+	}
+
+	var stripped []Boilerplate
+	for key, n := range counts {
+		if float64(n) > threshold*float64(files) {
+			stripped = append(stripped, Boilerplate{Line: key, Files: n})
+		}
+	}
+	if len(stripped) == 0 {
+		return nil
+	}
+	sort.Slice(stripped, func(i, j int) bool {
+		if stripped[i].Files != stripped[j].Files {
+			return stripped[i].Files > stripped[j].Files
+		}
+		return stripped[i].Line < stripped[j].Line
+	})
+	boilerplate := make(map[string]bool, len(stripped))
+	for _, b := range stripped {
+		boilerplate[b.Line] = true
+	}
+
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		result := &results[i].Result
+		keys := lineKeys(result.Sentences)
+		kept := result.Sentences[:0]
+		var dropped []Sentence
+		for _, s := range result.Sentences {
+			if !boilerplate[keys[s.SourceLine]] {
+				kept = append(kept, s)
+			} else {
+				dropped = append(dropped, s)
+			}
+		}
+		result.Sentences = kept
+		result.reject(dropped, RejectedBoilerplate, opts)
+		opts.buildStreams(result)
+	}
+	return stripped
+}
+
+// lineKeys joins the fragments of each source line into the key that line is compared by.
+func lineKeys(sentences []Sentence) map[int]string {
+	keys := map[int]string{}
+	for _, s := range sentences {
+		keys[s.SourceLine] += s.Text
+	}
+	return keys
+}
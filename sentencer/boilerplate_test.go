@@ -0,0 +1,57 @@
+package sentencer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestStripBoilerplate(t *testing.T) {
+	header := "某某出版社 版权所有"
+	texts := []string{
+		header + "\n第一章。正文甲。\n第 1 页",
+		header + "\n第二章。正文乙。\n第 2 页",
+		header + "\n第三章。正文丙。\n第 1 页",
+	}
+	opts := withOptions(func(o *Options) { o.KeepRejected = true })
+	var results []FileResult
+	for i, text := range texts {
+		results = append(results, FileResult{Path: string(rune('a'+i)) + ".txt", Result: ProcessText(text, opts)})
+	}
+	results = append(results, FileResult{Path: "broken.txt", Err: errors.New("unreadable")})
+
+	stripped := StripBoilerplate(results, 0.5, opts)
+	want := []Boilerplate{{Line: header, Files: 3}, {Line: "第 1 页", Files: 2}}
+	if !reflect.DeepEqual(stripped, want) {
+		t.Errorf("stripped = %+v, want %+v", stripped, want)
+	}
+	wantCombined := [][]string{
+		{"第一章。", "正文甲。"},
+		{"第二章。", "正文乙。", "第 2 页"},
+		{"第三章。", "正文丙。"},
+	}
+	for i, w := range wantCombined {
+		r := results[i].Result
+		if !reflect.DeepEqual(r.Combined, w) {
+			t.Errorf("%s: Combined = %q, want %q", results[i].Path, r.Combined, w)
+		}
+		if r.Stats.Sentences != len(w) {
+			t.Errorf("%s: Stats.Sentences = %d, want %d", results[i].Path, r.Stats.Sentences, len(w))
+		}
+		for _, rej := range r.Rejected {
+			if rej.Filter != RejectedBoilerplate {
+				t.Errorf("%s: rejected %q by %q, want boilerplate", results[i].Path, rej.Text, rej.Filter)
+			}
+		}
+	}
+}
+
+func TestStripBoilerplateNeedsTwoFiles(t *testing.T) {
+	results := []FileResult{{Path: "a.txt", Result: ProcessText("页眉\n正文。", DefaultOptions())}}
+	if stripped := StripBoilerplate(results, 0.5, DefaultOptions()); stripped != nil {
+		t.Errorf("stripped %+v from a single file", stripped)
+	}
+	if got := results[0].Result.Combined; len(got) != 2 {
+		t.Errorf("Combined = %q, want the file untouched", got)
+	}
+}
@@ -0,0 +1,51 @@
+package sentencer
+
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+)
+
+// Match is one match of the split pattern in an input line, as reported by DebugMatches.
+type Match struct {
+	Start, End int    // Rune offsets of the match in the line, End exclusive
+	Text       string // The matched punctuation
+}
+
+// LineMatches lists the matches of the split pattern in one input line.
+type LineMatches struct {
+	Line    int // 1-based line number
+	Text    string
+	Matches []Match
+}
+
+// SplitPattern returns the regular expression a line is split after, as built from opts.
+func (o Options) SplitPattern() string {
+	return o.punctuationRegex().String()
+}
+
+// DebugMatches reports where the split pattern matches in each of the first maxLines lines of r,
+// before any cleaning, to help tune Terminators and SplitEnum. Offsets are in runes so they can be
+// counted by eye in Chinese text.
+func DebugMatches(r io.Reader, maxLines int, opts Options) ([]LineMatches, error) {
+	re := opts.punctuationRegex()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	scanner.Split(ScanLines)
+
+	var lines []LineMatches
+	for len(lines) < maxLines && scanner.Scan() {
+		line := scanner.Text()
+		lm := LineMatches{Line: len(lines) + 1, Text: line}
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			start := utf8.RuneCountInString(line[:loc[0]])
+			lm.Matches = append(lm.Matches, Match{
+				Start: start,
+				End:   start + utf8.RuneCountInString(line[loc[0]:loc[1]]),
+				Text:  line[loc[0]:loc[1]],
+			})
+		}
+		lines = append(lines, lm)
+	}
+	return lines, scanner.Err()
+}
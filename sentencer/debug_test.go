@@ -0,0 +1,43 @@
+package sentencer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDebugMatches(t *testing.T) {
+	lines, err := DebugMatches(strings.NewReader("你好，世界。\nno marks\n第三行、不读"), 2, DefaultOptions())
+	if err != nil {
+		t.Fatalf("DebugMatches: %v", err)
+	}
+	want := []LineMatches{
+		{Line: 1, Text: "你好，世界。", Matches: []Match{{Start: 2, End: 3, Text: "，"}, {Start: 5, End: 6, Text: "。"}}},
+		{Line: 2, Text: "no marks"},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("DebugMatches = %+v, want %+v", lines, want)
+	}
+}
+
+func TestSplitPattern(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(o *Options)
+		want      string
+	}{
+		{"custom", func(o *Options) { o.Terminators = "。!" }, "([。!])"},
+		{"escaped", func(o *Options) { o.Terminators = "]-" }, `([\]\-])`},
+	}
+	for _, tt := range tests {
+		if got := withOptions(tt.configure).SplitPattern(); got != tt.want {
+			t.Errorf("%s: SplitPattern = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := DefaultOptions().SplitPattern(); !strings.Contains(got, "、") {
+		t.Errorf("default SplitPattern %q lacks the enumeration comma", got)
+	}
+	if got := withOptions(func(o *Options) { o.SplitEnum = false }).SplitPattern(); strings.Contains(got, "、") {
+		t.Errorf("SplitPattern %q with SplitEnum off has the enumeration comma", got)
+	}
+}
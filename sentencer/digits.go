@@ -0,0 +1,174 @@
+package sentencer
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Digit forms for Options.DigitForm.
+const (
+	DigitFormASCII     = "ascii"     // 0-9; full-width digits and Chinese numerals (see hanziNumber) are converted
+	DigitFormFullWidth = "fullwidth" // ０-９; ASCII digits and Chinese numerals are converted
+	DigitFormHanzi     = "hanzi"     // 〇一二三四五六七八九, digit by digit, in non-English fragments; English ones get 0-9
+)
+
+// hanziDigits maps the Chinese digit characters to their values. 两 is only a digit before a
+// unit, as in 两百.
+var hanziDigits = map[rune]int64{
+	'〇': 0, '零': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+// hanziUnits maps the Chinese unit characters to their values: 十百千 inside a section and 万亿
+// closing one.
+var hanziUnits = map[rune]int64{'十': 10, '百': 100, '千': 1000, '万': 10000, '亿': 100000000}
+
+// hanziDigitForms are the characters DigitFormHanzi writes for 0-9.
+var hanziDigitForms = []rune("〇一二三四五六七八九")
+
+// maxHanziRun is the longest run of Chinese numerals converted; longer ones could overflow.
+const maxHanziRun = 18
+
+// convertDigits rewrites the digits of text in the given DigitForm.
+func convertDigits(text, form string) string {
+	text = hanziNumbersToASCII(text)
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '０' && r <= '９':
+			r = r - '０' + '0'
+		case r < '0' || r > '9':
+			return r
+		}
+		switch form {
+		case DigitFormFullWidth:
+			return r - '0' + '０'
+		case DigitFormHanzi:
+			return hanziDigitForms[r-'0']
+		default:
+			return r
+		}
+	}, text)
+}
+
+// hanziNumbersToASCII replaces every run of Chinese numerals that hanziNumber can read with its
+// ASCII digits, leaving the other runs as they are.
+func hanziNumbersToASCII(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !isHanziNumeral(r) {
+			b.WriteString(text[i : i+size])
+			i += size
+			continue
+		}
+		end := i
+		for end < len(text) {
+			next, nextSize := utf8.DecodeRuneInString(text[end:])
+			if !isHanziNumeral(next) {
+				break
+			}
+			end += nextSize
+		}
+		if digits, ok := hanziNumber(text[i:end]); ok {
+			b.WriteString(digits)
+		} else {
+			b.WriteString(text[i:end])
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// isHanziNumeral reports whether r is a Chinese digit or unit character.
+func isHanziNumeral(r rune) bool {
+	_, digit := hanziDigits[r]
+	_, unit := hanziUnits[r]
+	return digit || unit
+}
+
+// hanziNumber reads a run of Chinese numerals as ASCII digits. Only basic numbers are read: a run
+// of digits alone is read digit by digit when it has a 〇 or 零 or at least 3 digits (二〇二一 is
+// 2021), and one with units positionally (三百二十一 is 321, 十五 is 15, 一亿二千万 is 120000000,
+// with a trailing digit after a unit abbreviating the next lower one, as in 三百五 for 350). Runs
+// of a single character, of two digits without 〇 or 零 (approximations such as 七八个 and words
+// such as 一一对应), without any digit, with a unit not preceded by a digit (except a leading 十),
+// or longer than maxHanziRun are not read, so words such as 一些, 统一, 千万 or 万一 stay as they
+// are. Formal forms (壹贰叁), fractions and decimals (三点五) are not supported.
+func hanziNumber(run string) (string, bool) {
+	runes := []rune(run)
+	if len(runes) < 2 || len(runes) > maxHanziRun {
+		return "", false
+	}
+	hasDigit, hasUnit := false, false
+	for _, r := range runes {
+		if _, ok := hanziDigits[r]; ok {
+			hasDigit = true
+		}
+		if _, ok := hanziUnits[r]; ok {
+			hasUnit = true
+		}
+	}
+	if !hasDigit {
+		return "", false
+	}
+	if !hasUnit {
+		if len(runes) < 3 && !strings.ContainsAny(run, "〇零") {
+			return "", false // 七八 and 三四 are approximations, 一一 a word
+		}
+		var b strings.Builder
+		for _, r := range runes {
+			if r == '两' {
+				return "", false
+			}
+			b.WriteByte(byte('0' + hanziDigits[r]))
+		}
+		return b.String(), true
+	}
+
+	var total, section, number, lastUnit, sectionUnit int64
+	pendingDigit, afterZero := false, false
+	for i, r := range runes {
+		if d, ok := hanziDigits[r]; ok {
+			if pendingDigit {
+				return "", false // Two digits in a row, as in 一二百
+			}
+			if d == 0 {
+				afterZero = true
+				continue
+			}
+			number, pendingDigit = d, true
+			continue
+		}
+		unit := hanziUnits[r]
+		switch {
+		case unit < 10000:
+			if sectionUnit != 0 && unit >= sectionUnit {
+				return "", false // Units must decrease within a section, unlike 五十五十
+			}
+			if !pendingDigit {
+				if r != '十' || i > 0 && !afterZero {
+					return "", false
+				}
+				number = 1 // 十五 is 15 and 一千零十 is 1010
+			}
+			section += number * unit
+			sectionUnit = unit
+		default:
+			section += number
+			if section == 0 {
+				return "", false
+			}
+			if unit == 100000000 {
+				total = (total + section) * unit
+			} else {
+				total += section * unit
+			}
+			section, sectionUnit = 0, 0
+		}
+		number, pendingDigit, afterZero, lastUnit = 0, false, false, unit
+	}
+	if pendingDigit && !afterZero && lastUnit >= 100 {
+		number *= lastUnit / 10 // 三百五 is 350, 一万五 is 15000
+	}
+	return strconv.FormatInt(total+section+number, 10), true
+}
@@ -0,0 +1,71 @@
+package sentencer
+
+import "testing"
+
+func TestHanziNumber(t *testing.T) {
+	tests := []struct {
+		run    string
+		want   string
+		wantOK bool
+	}{
+		{"二〇二一", "2021", true},
+		{"一二三", "123", true},
+		{"一〇", "10", true},
+		{"三百二十一", "321", true},
+		{"十五", "15", true},
+		{"一万五", "15000", true},
+		{"三百五", "350", true},
+		{"一千零十", "1010", true},
+		{"两百", "200", true},
+		{"一亿二千万", "120000000", true},
+		{"一", "", false},
+		{"七八", "", false},
+		{"一一", "", false},
+		{"千万", "", false},
+		{"万一", "", false},
+		{"一二百", "", false},
+		{"五十五十", "", false},
+		{"两两两", "", false},
+		{"一二三四五六七八九一二三四五六七八九", "123456789123456789", true},
+		{"一二三四五六七八九一二三四五六七八九〇", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := hanziNumber(tt.run)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("hanziNumber(%q) = %q, %v, want %q, %v", tt.run, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestDigitForm(t *testing.T) {
+	tests := []struct {
+		input, form, want string
+	}{
+		{"编号１２３", DigitFormASCII, "编号123"},
+		{"编号一二三", DigitFormASCII, "编号123"},
+		{"二〇二一年", DigitFormASCII, "2021年"},
+		{"三百二十一人", DigitFormASCII, "321人"},
+		{"编号123", DigitFormFullWidth, "编号１２３"},
+		{"编号一二三", DigitFormFullWidth, "编号１２３"},
+		{"2021年", DigitFormHanzi, "二〇二一年"},
+		{"Room 101", DigitFormHanzi, "Room 101"},
+		{"Room １０１", DigitFormHanzi, "Room 101"},
+		{"一些人统一说万一", DigitFormASCII, "一些人统一说万一"},
+		{"七八个", DigitFormASCII, "七八个"},
+		{"十二三岁", DigitFormASCII, "十二三岁"},
+		{"三点五", DigitFormASCII, "三点五"},
+	}
+	for _, tt := range tests {
+		opts := withOptions(func(o *Options) { o.DigitForm = tt.form })
+		if got := opts.normalize(tt.input); got != tt.want {
+			t.Errorf("normalize(%q) under %s = %q, want %q", tt.input, tt.form, got, tt.want)
+		}
+	}
+}
+
+func TestDigitFormHanziKeepsEnglishStream(t *testing.T) {
+	result := ProcessText("Room 101", withOptions(func(o *Options) { o.DigitForm = DigitFormHanzi }))
+	if len(result.English) != 1 || result.English[0] != "Room 101" {
+		t.Errorf("English = %q, want [Room 101]", result.English)
+	}
+}
@@ -0,0 +1,39 @@
+package sentencer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Categories of a sentence's terminal punctuation, as reported by EndType.
+const (
+	EndStatement   = "statement"   // 。, its vertical form ︒ or .
+	EndExclamation = "exclamation" // ！, ︕ or !
+	EndQuestion    = "question"    // ？, ︖ or ?
+	EndOther       = "other"       // Any other punctuation mark, e.g. ， or ；
+	EndNone        = "none"        // No terminal punctuation
+)
+
+// closingMarks are quotes and brackets that may follow the terminal punctuation, as in 真好！”
+const closingMarks = "”’」』）)】》〉\"'"
+
+// EndType classifies the punctuation that ends text, looking past closing quotes and brackets.
+func EndType(text string) string {
+	text = strings.TrimRight(strings.TrimRightFunc(text, unicode.IsSpace), closingMarks)
+	r, size := utf8.DecodeLastRuneInString(text)
+	switch {
+	case size == 0:
+		return EndNone
+	case r == '。' || r == '︒' || r == '.':
+		return EndStatement
+	case r == '！' || r == '︕' || r == '!':
+		return EndExclamation
+	case r == '？' || r == '︖' || r == '?':
+		return EndQuestion
+	case unicode.IsPunct(r):
+		return EndOther
+	default:
+		return EndNone
+	}
+}
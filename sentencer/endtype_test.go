@@ -0,0 +1,29 @@
+package sentencer
+
+import "testing"
+
+func TestEndType(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"你好。", EndStatement},
+		{"Hello.", EndStatement},
+		{"竖排︒", EndStatement},
+		{"真好！", EndExclamation},
+		{"Wow!", EndExclamation},
+		{"对吗？", EndQuestion},
+		{"Really?", EndQuestion},
+		{"他说：“今天天气真好！”", EndExclamation},
+		{"(Is it?)  ", EndQuestion},
+		{"你好，", EndOther},
+		{"甲；", EndOther},
+		{"没有标点", EndNone},
+		{"", EndNone},
+		{"”", EndNone},
+	}
+	for _, tt := range tests {
+		if got := EndType(tt.text); got != tt.want {
+			t.Errorf("EndType(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package sentencer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// englishAbbreviations are lower-cased words that end in a full stop without ending a sentence,
+// as in "Dr. Smith" or "e.g. this". Initials such as "J. R. R." are handled apart.
+var englishAbbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sr": true, "jr": true, "st": true,
+	"mt": true, "vs": true, "etc": true, "e.g": true, "i.e": true, "cf": true, "al": true,
+	"vol": true, "fig": true, "p": true, "pp": true, "inc": true, "ltd": true, "co": true,
+	"jan": true, "feb": true, "mar": true, "apr": true, "jun": true, "jul": true, "aug": true,
+	"sep": true, "sept": true, "oct": true, "nov": true, "dec": true, "u.s": true, "u.k": true,
+}
+
+// englishOpeningMarks may start a sentence before its first letter, as in "Why?" he asked.
+const englishOpeningMarks = "\"'“‘(["
+
+// splitEnglishSentences cuts text into English sentences, which the Chinese punctuation marks
+// leave whole, without cutting at every full stop as a "." terminator would. A sentence ends after
+// . ! or ?, optionally followed by closing quotes or brackets, when whitespace and then an
+// upper-case letter, a digit, a Han character or an opening quote follow. A full stop after a
+// known abbreviation or a single-letter initial does not end a sentence, and a full stop not
+// followed by whitespace, as in 3.14 or example.com, never does. The pieces keep the whitespace
+// between sentences, so they concatenate back to text.
+func splitEnglishSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+		end := i
+		for end < len(text) {
+			closing, closingSize := utf8.DecodeRuneInString(text[end:])
+			if !strings.ContainsRune(closingMarks, closing) {
+				break
+			}
+			end += closingSize
+		}
+		next := end
+		for next < len(text) && (text[next] == ' ' || text[next] == '\t') {
+			next++
+		}
+		if next == end || next == len(text) || !startsSentence(text[next:]) {
+			continue
+		}
+		if r == '.' && isAbbreviation(text[start:i-size]) {
+			continue
+		}
+		sentences = append(sentences, text[start:next])
+		start, i = next, next
+	}
+	return append(sentences, text[start:])
+}
+
+// startsSentence reports whether s begins like a new sentence: with an upper-case letter, a digit
+// or a Han character, possibly after opening quotes or brackets.
+func startsSentence(s string) bool {
+	s = strings.TrimLeft(s, englishOpeningMarks)
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsUpper(r) || unicode.IsDigit(r) || ScriptOf(r) == ScriptChinese
+}
+
+// isAbbreviation reports whether the last word of before, the text ahead of a full stop, is a known
+// abbreviation or a single upper-case initial other than the pronoun I.
+func isAbbreviation(before string) bool {
+	word := before
+	if i := strings.LastIndexFunc(before, isWordBoundary); i >= 0 {
+		_, size := utf8.DecodeRuneInString(before[i:])
+		word = before[i+size:]
+	}
+	if r, size := utf8.DecodeRuneInString(word); size == len(word) && unicode.IsUpper(r) && r != 'I' {
+		return true
+	}
+	return englishAbbreviations[strings.ToLower(word)]
+}
+
+// isWordBoundary reports whether r separates the word before a full stop from the text ahead of it.
+func isWordBoundary(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(englishOpeningMarks, r)
+}
+
+// cutAfterEnglishSemicolons cuts text after every ASCII semicolon followed by whitespace, so the
+// independent clauses of "It rained; we stayed in." become sentences of their own. A semicolon
+// not followed by whitespace, as in an HTML entity, does not cut.
+func cutAfterEnglishSemicolons(text string) []string {
+	var pieces []string
+	start := 0
+	for i := 0; i+1 < len(text); i++ {
+		if text[i] == ';' && (text[i+1] == ' ' || text[i+1] == '\t') {
+			pieces = append(pieces, text[start:i+1])
+			start = i + 1
+		}
+	}
+	return append(pieces, text[start:])
+}
@@ -0,0 +1,54 @@
+package sentencer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitEnglishSentences(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Dr. Smith went home. He slept.", []string{"Dr. Smith went home. ", "He slept."}},
+		{"Mr. and Mrs. Jones came. They left.", []string{"Mr. and Mrs. Jones came. ", "They left."}},
+		{"See e.g. the notes. Then stop.", []string{"See e.g. the notes. ", "Then stop."}},
+		{"J. R. R. Tolkien wrote it.", []string{"J. R. R. Tolkien wrote it."}},
+		{"So did I. Then we left.", []string{"So did I. ", "Then we left."}},
+		{"Pi is 3.14 today. Visit example.com now.", []string{"Pi is 3.14 today. ", "Visit example.com now."}},
+		{`He asked "Why?" Then left.`, []string{`He asked "Why?" `, "Then left."}},
+		{`"Stop!" she said. "Now."`, []string{`"Stop!" she said. `, `"Now."`}},
+		{"It works (mostly.) Next one.", []string{"It works (mostly.) ", "Next one."}},
+		{"Wait... what? 3 more.", []string{"Wait... what? ", "3 more."}},
+		{"He left. 然后我们走了。", []string{"He left. ", "然后我们走了。"}},
+		{"not a start. lower case follows.", []string{"not a start. lower case follows."}},
+	}
+	for _, tt := range tests {
+		got := splitEnglishSentences(tt.text)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitEnglishSentences(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+		if joined := strings.Join(got, ""); joined != tt.text {
+			t.Errorf("splitEnglishSentences(%q) pieces join to %q", tt.text, joined)
+		}
+	}
+}
+
+func TestCutAfterEnglishSemicolons(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"It rained; we stayed in.", []string{"It rained;", " we stayed in."}},
+		{"a;\tb; c", []string{"a;", "\tb;", " c"}},
+		{"&amp; and &lt;x", []string{"&amp;", " and &lt;x"}},
+		{"x;y", []string{"x;y"}},
+		{"end;", []string{"end;"}},
+	}
+	for _, tt := range tests {
+		if got := cutAfterEnglishSemicolons(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cutAfterEnglishSemicolons(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package sentencer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Errors reported in FileResult.Err for files that are not worth processing.
+var (
+	ErrEmptyFile  = errors.New("file is empty")
+	ErrBinaryFile = errors.New("file looks binary, not text")
+)
+
+// binarySniffSize is how much of a file is inspected for NUL bytes, and binaryNULRatio the
+// share of NUL bytes in it above which the file is treated as binary.
+const (
+	binarySniffSize = 8000
+	binaryNULRatio  = 0.1
+)
+
+// FileResult is the outcome of processing one file with ProcessFiles.
+type FileResult struct {
+	Path   string
+	Result Result
+	Err    error // Set when the file could not be read or is empty or binary; Result is then empty
+
+	// Partial is set when the context ended while the file was being split. Err is then the
+	// context's error and Result holds the sentences of the lines handled so far.
+	Partial bool
+}
+
+// ProcessFiles reads and processes the files concurrently, running at most opts.Workers
+// files at a time (one when Workers is not positive). Files are opened with opts.Open,
+// or os.Open when it is nil. The results are returned in the order of paths; the error is
+// the first per-file error in that order, so callers can see at a glance that one failed.
+func ProcessFiles(paths []string, opts Options) ([]FileResult, error) {
+	return ProcessFilesContext(context.Background(), paths, opts)
+}
+
+// ProcessFilesContext is ProcessFiles stopping early when ctx ends: files not started by then
+// get ctx's error, and files being split keep what was done so far and are marked Partial.
+func ProcessFilesContext(ctx context.Context, paths []string, opts Options) ([]FileResult, error) {
+	results := make([]FileResult, 0, len(paths))
+	ProcessFilesFunc(ctx, paths, opts, func(r FileResult) error {
+		results = append(results, r)
+		return nil
+	})
+	for _, r := range results {
+		if r.Err != nil {
+			return results, fmt.Errorf("%s: %w", r.Path, r.Err)
+		}
+	}
+	return results, nil
+}
+
+// ProcessFilesFunc is ProcessFilesContext handing each FileResult to fn, in the order of paths,
+// as soon as it and those before it are done, instead of returning them all at the end. A file's
+// worker slot is only freed once fn has returned for it, so at most opts.Workers results are held
+// at a time, however large the batch, and fn can write each result out and drop it. An error
+// returned by fn stops the remaining files and is returned; per-file errors are in the results.
+func ProcessFilesFunc(ctx context.Context, paths []string, opts Options, fn func(FileResult) error) error {
+	open := opts.Open
+	if open == nil {
+		open = func(path string) (io.ReadCloser, error) { return os.Open(path) }
+	}
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type done struct {
+		result FileResult
+		slot   bool // Whether the file took a worker slot, to be freed once fn returns
+	}
+	pending := make([]chan done, len(paths))
+	for i := range pending {
+		pending[i] = make(chan done, 1)
+	}
+	slots := make(chan struct{}, workers)
+	go func() {
+		for i, path := range paths {
+			acquired := false
+			select {
+			case slots <- struct{}{}:
+				acquired = true
+			case <-ctx.Done():
+			}
+			if err := ctx.Err(); err != nil {
+				if acquired {
+					<-slots // Taken just as ctx ended
+				}
+				pending[i] <- done{result: FileResult{Path: path, Err: err}}
+				continue
+			}
+			go func(i int, path string) {
+				pending[i] <- done{result: processFile(ctx, path, open, opts), slot: true}
+			}(i, path)
+		}
+	}()
+
+	for _, p := range pending {
+		d := <-p
+		err := fn(d.result)
+		if d.slot {
+			<-slots
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processFile reads one file through open and runs ProcessTextContext on its content.
+func processFile(ctx context.Context, path string, open func(path string) (io.ReadCloser, error), opts Options) FileResult {
+	readStart := time.Now()
+	file, err := open(path)
+	if err != nil {
+		return FileResult{Path: path, Err: err}
+	}
+	defer file.Close()
+
+	var content []byte
+	if opts.ToLine > 0 {
+		content, err = readLines(file, opts.ToLine)
+	} else {
+		content, err = io.ReadAll(file)
+	}
+	if err != nil {
+		return FileResult{Path: path, Err: err}
+	}
+	if len(content) == 0 {
+		return FileResult{Path: path, Err: ErrEmptyFile}
+	}
+	if looksBinary(content) {
+		return FileResult{Path: path, Err: ErrBinaryFile}
+	}
+	readTime := time.Since(readStart)
+
+	result, err := ProcessTextContext(ctx, string(content), opts)
+	result.Stats.Timings.Read = readTime
+	return FileResult{Path: path, Result: result, Err: err, Partial: err != nil}
+}
+
+// readLines reads r up to the end of its 1-based line n, where ScanLines ends it, so the lines
+// after n are never read.
+func readLines(r io.Reader, n int) ([]byte, error) {
+	reader := bufio.NewReader(r)
+	var content []byte
+	for lines := 0; lines < n; {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, b)
+		switch b {
+		case '\n':
+			lines++
+		case '\r':
+			if next, err := reader.Peek(1); err != nil || next[0] != '\n' {
+				lines++ // A lone \r; the \n of \r\n ends the line instead
+			}
+		}
+	}
+	return content, nil
+}
+
+// looksBinary reports whether a high proportion of the first bytes of content are NUL bytes,
+// which text in any of the supported encodings does not contain.
+func looksBinary(content []byte) bool {
+	sniff := content
+	if len(sniff) > binarySniffSize {
+		sniff = sniff[:binarySniffSize]
+	}
+	return float64(bytes.Count(sniff, []byte{0})) > binaryNULRatio*float64(len(sniff))
+}
@@ -0,0 +1,198 @@
+package sentencer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// fixtureOpener serves files from memory for Options.Open, failing for paths missing from files.
+func fixtureOpener(files map[string]string) func(path string) (io.ReadCloser, error) {
+	return func(path string) (io.ReadCloser, error) {
+		content, ok := files[path]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return io.NopCloser(strings.NewReader(content)), nil
+	}
+}
+
+func TestProcessFiles(t *testing.T) {
+	files := map[string]string{
+		"a.txt":     "你好，世界。",
+		"b.txt":     "Hello there",
+		"empty.txt": "",
+		"bin.dat":   "PK\x00\x00\x00\x00\x00\x00\x00\x00",
+		"c.txt":     "再见。",
+	}
+	paths := []string{"a.txt", "b.txt", "empty.txt", "bin.dat", "missing.txt", "c.txt"}
+	opts := withOptions(func(o *Options) { o.Workers = 3; o.Open = fixtureOpener(files) })
+
+	results, err := ProcessFiles(paths, opts)
+	if err == nil || !errors.Is(err, ErrEmptyFile) {
+		t.Errorf("err = %v, want the first per-file error, ErrEmptyFile", err)
+	}
+	tests := []struct {
+		path    string
+		want    []string
+		wantErr error
+	}{
+		{"a.txt", []string{"你好，", "世界。"}, nil},
+		{"b.txt", []string{"Hello there"}, nil},
+		{"empty.txt", nil, ErrEmptyFile},
+		{"bin.dat", nil, ErrBinaryFile},
+		{"missing.txt", nil, os.ErrNotExist},
+		{"c.txt", []string{"再见。"}, nil},
+	}
+	if len(results) != len(tests) {
+		t.Fatalf("got %d results, want %d", len(results), len(tests))
+	}
+	for i, tt := range tests {
+		r := results[i]
+		if r.Path != tt.path {
+			t.Errorf("result %d: Path = %q, want %q", i, r.Path, tt.path)
+		}
+		if !errors.Is(r.Err, tt.wantErr) {
+			t.Errorf("%s: Err = %v, want %v", tt.path, r.Err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(r.Result.Combined, tt.want) {
+			t.Errorf("%s: Combined = %q, want %q", tt.path, r.Result.Combined, tt.want)
+		}
+	}
+}
+
+func TestLooksBinary(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"纯文本，没有空字节。", false},
+		{"text with one \x00 NUL among many characters", false},
+		{"\x00\x00\x00\x00abcd", true},
+		{strings.Repeat("a", binarySniffSize) + strings.Repeat("\x00", 1000), false},
+	}
+	for _, tt := range tests {
+		if got := looksBinary([]byte(tt.content)); got != tt.want {
+			t.Errorf("looksBinary(%.20q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	tests := []struct {
+		content string
+		n       int
+		want    string
+	}{
+		{"一\n二\n三", 2, "一\n二\n"},
+		{"一\r\n二\r三\n", 2, "一\r\n二\r"},
+		{"一\r", 1, "一\r"},
+		{"一\n二", 5, "一\n二"},
+	}
+	for _, tt := range tests {
+		got, err := readLines(strings.NewReader(tt.content), tt.n)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("readLines(%q, %d) = %q, %v, want %q", tt.content, tt.n, got, err, tt.want)
+		}
+	}
+
+	// Reading past line n would hit the error
+	past := io.MultiReader(strings.NewReader("一\n二\n"), iotest.ErrReader(errors.New("read past line 2")))
+	if got, err := readLines(past, 2); err != nil || string(got) != "一\n二\n" {
+		t.Errorf("readLines = %q, %v, want the first two lines only", got, err)
+	}
+}
+
+func TestProcessFilesFuncOrderAndWorkers(t *testing.T) {
+	files := map[string]string{}
+	var paths []string
+	for i := 0; i < 20; i++ {
+		path := string(rune('a'+i)) + ".txt"
+		files[path] = strings.Repeat("你好。", 20-i)
+		paths = append(paths, path)
+	}
+	var open, maxOpen int32
+	opener := fixtureOpener(files)
+	opts := withOptions(func(o *Options) {
+		o.Workers = 4
+		o.Open = func(path string) (io.ReadCloser, error) {
+			if n := atomic.AddInt32(&open, 1); n > atomic.LoadInt32(&maxOpen) {
+				atomic.StoreInt32(&maxOpen, n)
+			}
+			time.Sleep(time.Millisecond)
+			return opener(path)
+		}
+	})
+
+	var got []string
+	err := ProcessFilesFunc(context.Background(), paths, opts, func(r FileResult) error {
+		atomic.AddInt32(&open, -1)
+		got = append(got, r.Path)
+		if want := 20 - len(got) + 1; len(r.Result.Combined) != want {
+			t.Errorf("%s: %d sentences, want %d", r.Path, len(r.Result.Combined), want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ProcessFilesFunc: %v", err)
+	}
+	if !reflect.DeepEqual(got, paths) {
+		t.Errorf("results in order %q, want %q", got, paths)
+	}
+	if maxOpen > 4 {
+		t.Errorf("%d files held at once, want at most 4 workers", maxOpen)
+	}
+}
+
+func TestProcessFilesFuncStopsOnError(t *testing.T) {
+	files := map[string]string{"a.txt": "一。", "b.txt": "二。", "c.txt": "三。"}
+	opts := withOptions(func(o *Options) { o.Workers = 2; o.Open = fixtureOpener(files) })
+	stop := errors.New("stop")
+	var seen []string
+	err := ProcessFilesFunc(context.Background(), []string{"a.txt", "b.txt", "c.txt"}, opts, func(r FileResult) error {
+		seen = append(seen, r.Path)
+		if r.Path == "b.txt" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("err = %v, want the error returned by fn", err)
+	}
+	if want := []string{"a.txt", "b.txt"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("fn saw %q, want %q", seen, want)
+	}
+}
+
+func TestProcessFilesContextTimeout(t *testing.T) {
+	large := strings.Repeat("这是一个很长的句子，用来测试超时。\n", 200000)
+	files := map[string]string{"large.txt": large, "later.txt": "不会被处理。"}
+	opts := withOptions(func(o *Options) { o.Workers = 1; o.Open = fixtureOpener(files) })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	results, err := ProcessFilesContext(ctx, []string{"large.txt", "later.txt"}, opts)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	first := results[0]
+	if !first.Partial || !errors.Is(first.Err, context.DeadlineExceeded) {
+		t.Errorf("large.txt: Partial = %v, Err = %v, want a partial result cut by the deadline", first.Partial, first.Err)
+	}
+	if first.Result.Stats.Lines >= 200000 {
+		t.Errorf("large.txt: all %d lines were read despite the deadline", first.Result.Stats.Lines)
+	}
+	if len(first.Result.Sentences) != 2*first.Result.Stats.Lines {
+		t.Errorf("large.txt: %d sentences from %d lines, want two per line read", len(first.Result.Sentences), first.Result.Stats.Lines)
+	}
+	if later := results[1]; later.Partial || !errors.Is(later.Err, context.DeadlineExceeded) || len(later.Result.Sentences) != 0 {
+		t.Errorf("later.txt: %+v, want it skipped with the deadline error", later)
+	}
+}
@@ -0,0 +1,391 @@
+package sentencer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// asciiToFullWidth maps ASCII punctuation to its full-width Chinese form. The full stop is left
+// out because it is ambiguous with decimal points and abbreviations.
+var asciiToFullWidth = map[rune]rune{
+	',': '，', '!': '！', '?': '？', ':': '：', ';': '；', '(': '（', ')': '）',
+}
+
+// fullWidthToASCII maps full-width Chinese punctuation to its ASCII form.
+var fullWidthToASCII = map[rune]rune{
+	'，': ',', '！': '!', '？': '?', '：': ':', '；': ';', '（': '(', '）': ')', '。': '.',
+}
+
+// curlyToStraightQuotes maps typographic quotes and apostrophes to their ASCII forms.
+var curlyToStraightQuotes = map[rune]rune{
+	'“': '"', '”': '"', '„': '"', '‘': '\'', '’': '\'', '‚': '\'',
+}
+
+// dashes are the dash and hyphen characters Options.UnifyDashes maps to one form besides the ASCII
+// hyphen-minus: hyphen, non-breaking hyphen, figure dash, en dash, em dash, horizontal bar, minus
+// sign, and the small and full-width hyphen-minus.
+const dashes = "‐‑‒–—―−﹣－"
+
+// chineseDash is the Chinese dash 破折号 that Options.UnifyDashes writes next to Chinese text.
+const chineseDash = "——"
+
+// isDash reports whether r is one of dashes or the ASCII hyphen-minus.
+func isDash(r rune) bool {
+	return r == '-' || strings.ContainsRune(dashes, r)
+}
+
+// unifyDashes maps every run of dashes in line to one form, from the text around it: a run next to
+// a Han character, ignoring spaces, becomes chineseDash, and any other run is kept as that many
+// ASCII hyphens. A single ASCII hyphen, as in 2021-01-01 or 中-英, is always kept. It runs before
+// splitting, because — is a split mark: the dashes of 你好——世界 would otherwise already be cut into
+// fragments of their own.
+func unifyDashes(line string) string {
+	if !strings.ContainsAny(line, dashes) && !strings.Contains(line, "--") {
+		return line
+	}
+	runes := []rune(line)
+	// nextToHan reports whether the first non-space rune from i in direction step is a Han character
+	nextToHan := func(i, step int) bool {
+		for ; i >= 0 && i < len(runes); i += step {
+			if !unicode.IsSpace(runes[i]) {
+				return unicode.Is(unicode.Han, runes[i])
+			}
+		}
+		return false
+	}
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		end := i
+		for end < len(runes) && isDash(runes[end]) {
+			end++
+		}
+		switch {
+		case end == i:
+			b.WriteRune(runes[i])
+			continue
+		case end-i == 1 && runes[i] == '-':
+			b.WriteRune('-')
+		case nextToHan(i-1, -1) || nextToHan(end, 1):
+			b.WriteString(chineseDash)
+		default:
+			b.WriteString(strings.Repeat("-", end-i))
+		}
+		i = end - 1
+	}
+	return b.String()
+}
+
+// collapsibleMarks are the terminal marks whose repeated runs -collapse-punct folds into one. The
+// ASCII full stop is left out because "..." is an ellipsis, not an emphatic full stop.
+const collapsibleMarks = "。！？!?"
+
+// tableGlyphs are the characters used to draw table columns in plain-text exports: the ASCII
+// backslash and pipe, the vertical presentation form ︱ and 丨, which almost only appears as a
+// fixed-width divider.
+const tableGlyphs = "\\|︱丨"
+
+// cutAfterTableGlyphs cuts fragment after every table glyph, so each cell ends up in its own piece.
+func cutAfterTableGlyphs(fragment string) []string {
+	var pieces []string
+	start := 0
+	for i, r := range fragment {
+		if strings.ContainsRune(tableGlyphs, r) {
+			end := i + utf8.RuneLen(r)
+			pieces = append(pieces, fragment[start:end])
+			start = end
+		}
+	}
+	return append(pieces, fragment[start:])
+}
+
+// trimTableGlyph removes the table glyph a piece of cutAfterTableGlyphs may end with.
+func trimTableGlyph(piece string) string {
+	r, size := utf8.DecodeLastRuneInString(piece)
+	if size > 0 && strings.ContainsRune(tableGlyphs, r) {
+		return piece[:len(piece)-size]
+	}
+	return piece
+}
+
+// dividerMarker replaces a divider line with Options.KeepDividers, and minDividerRunes is the
+// shortest run of one mark counted as a divider rather than incidental punctuation.
+const (
+	dividerMarker   = "---"
+	minDividerRunes = 3
+)
+
+// isDivider reports whether line, ignoring surrounding whitespace, is a run of at least
+// minDividerRunes identical punctuation marks or symbols, such as ————— or ＊＊＊.
+func isDivider(line string) bool {
+	line = strings.TrimSpace(line)
+	first, _ := utf8.DecodeRuneInString(line)
+	if !unicode.IsPunct(first) && !unicode.IsSymbol(first) {
+		return false
+	}
+	count := 0
+	for _, r := range line {
+		if r != first {
+			return false
+		}
+		count++
+	}
+	return count >= minDividerRunes
+}
+
+// preprocessLine applies the enabled transformations to an input line before it is split.
+func (o Options) preprocessLine(line string) string {
+	if o.StripInvisible {
+		line = stripInvisible(line)
+	}
+	if o.CollapsePunct {
+		line = collapseRepeatedMarks(line)
+	}
+	if o.StripDialogue {
+		line = stripDialogueDashes(line)
+	}
+	if o.UnifyDashes {
+		line = unifyDashes(line)
+	}
+	if o.StripRanges != "" {
+		line = stripRanges(line, o.StripRanges)
+	}
+	return line
+}
+
+// stripRanges removes from line every span enclosed by one of the delimiter pairs, delimiters
+// included, so 文本【注：xxx】更多 becomes 文本更多 with the pairs 【】. pairs alternates opening and
+// closing runes; see ValidateRangePairs. Spans may nest, and a span is only removed once its
+// opener is closed: an opener without a closer is kept as text, so a missing 】 cannot swallow the
+// rest of the line, and a closer without an opener is kept too.
+func stripRanges(line, pairs string) string {
+	delimiters := []rune(pairs)
+	closerOf := func(r rune) (rune, bool) {
+		for i := 0; i+1 < len(delimiters); i += 2 {
+			if delimiters[i] == r {
+				return delimiters[i+1], true
+			}
+		}
+		return 0, false
+	}
+	if strings.IndexFunc(line, func(r rune) bool { _, ok := closerOf(r); return ok }) < 0 {
+		return line
+	}
+
+	runes := []rune(line)
+	// spanEnd returns the index of the closer matching the opener at start, or -1 when it has none.
+	spanEnd := func(start int) int {
+		closer, _ := closerOf(runes[start])
+		expected := []rune{closer}
+		for j := start + 1; j < len(runes); j++ {
+			if inner, ok := closerOf(runes[j]); ok {
+				expected = append(expected, inner)
+			} else if runes[j] == expected[len(expected)-1] {
+				expected = expected[:len(expected)-1]
+				if len(expected) == 0 {
+					return j
+				}
+			}
+		}
+		return -1
+	}
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		if _, ok := closerOf(runes[i]); ok {
+			if end := spanEnd(i); end >= 0 {
+				i = end
+				continue
+			}
+		}
+		b.WriteRune(runes[i])
+	}
+	return b.String()
+}
+
+// sentenceEnds are the marks after which a dialogue dash starts a new utterance.
+const sentenceEnds = "。！？!?︒︕︖"
+
+// stripDialogueDashes removes the dash — or —— introducing speech in Chinese dialogue where it starts
+// a sentence: at the start of line or after a sentence-ending mark, ignoring whitespace in between.
+// Dashes inside a sentence, as in 你好——世界, are kept. It runs before splitting, because the
+// dashes would otherwise be cut off into fragments of their own.
+func stripDialogueDashes(line string) string {
+	if !strings.ContainsRune(line, '—') {
+		return line
+	}
+	var b strings.Builder
+	atStart := true
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if atStart && r == '—' {
+			for i+1 < len(runes) && runes[i+1] == '—' {
+				i++
+			}
+			atStart = false
+			continue
+		}
+		b.WriteRune(r)
+		if !unicode.IsSpace(r) {
+			atStart = strings.ContainsRune(sentenceEnds, r)
+		}
+	}
+	return b.String()
+}
+
+// invisibleChars are the format characters (category Cf) that render as nothing and only get in
+// the way of matching text: the soft hyphen, the Mongolian vowel separator, zero-width space,
+// non-joiner and joiner, the directional marks, the word joiner and invisible operators, and the
+// zero-width no-break space (BOM).
+const invisibleChars = "\u00ad\u180e\u200b\u200c\u200d\u200e\u200f\u2060\u2061\u2062\u2063\u2064\ufeff"
+
+// zeroWidthJoiner glues emoji into one glyph, as in the family emoji 👨‍👩‍👧.
+const zeroWidthJoiner = '\u200d'
+
+// stripInvisible removes the invisibleChars from line, except zero-width joiners between two emoji,
+// which would otherwise fall apart into separate glyphs.
+func stripInvisible(line string) string {
+	if !strings.ContainsAny(line, invisibleChars) {
+		return line
+	}
+	runes := []rune(line)
+	var b strings.Builder
+	for i, r := range runes {
+		if r == zeroWidthJoiner && i > 0 && i < len(runes)-1 && isEmojiPart(runes[i-1]) && isEmojiPart(runes[i+1]) {
+			b.WriteRune(r)
+			continue
+		}
+		if !strings.ContainsRune(invisibleChars, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isEmojiPart reports whether r can sit next to a zero-width joiner inside an emoji sequence: a
+// pictographic symbol, a skin tone modifier or the emoji variation selector.
+func isEmojiPart(r rune) bool {
+	return r >= 0x2000 && (unicode.Is(unicode.So, r) || unicode.Is(unicode.Sk, r) || r == '\ufe0f')
+}
+
+// collapseRepeatedMarks folds runs of one identical terminal mark, as in 真的吗？？？ or wow!!!,
+// into a single mark.
+func collapseRepeatedMarks(line string) string {
+	if !strings.ContainsAny(line, collapsibleMarks) {
+		return line
+	}
+	var b strings.Builder
+	var prev rune
+	for _, r := range line {
+		if r == prev && strings.ContainsRune(collapsibleMarks, r) {
+			continue
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
+
+// normalize applies the enabled per-sentence normalizations to a cleaned fragment.
+func (o Options) normalize(text string) string {
+	if o.IdeographicSpace {
+		text = collapseIdeographicSpaces(text)
+	}
+	if o.DespaceCJK {
+		text = despaceCJK(text)
+	}
+	script := DominantScript(text)
+	if o.DigitForm != "" {
+		form := o.DigitForm
+		if form == DigitFormHanzi && script == ScriptEnglish {
+			form = DigitFormASCII // Chinese numerals would turn an English fragment into a Chinese one
+		}
+		text = convertDigits(text, form)
+	}
+	switch script {
+	case ScriptChinese:
+		if o.CanonPunct {
+			text = mapPunctuation(text, asciiToFullWidth)
+		}
+	case ScriptEnglish:
+		if o.CanonPunctEnglish {
+			text = mapPunctuation(text, fullWidthToASCII)
+		}
+		if o.StraightQuotes {
+			text = mapPunctuation(text, curlyToStraightQuotes)
+		}
+	}
+	return text
+}
+
+// ideographicSpace is the full-width space U+3000 of Chinese layout text.
+const ideographicSpace = "\u3000"
+
+// collapseIdeographicSpaces folds runs of ideographic spaces inside text into one and removes them
+// from both ends, as used for indentation, whatever the trimming mode.
+func collapseIdeographicSpaces(text string) string {
+	if !strings.Contains(text, ideographicSpace) {
+		return text
+	}
+	var b strings.Builder
+	for i, part := range strings.Split(strings.Trim(text, ideographicSpace), ideographicSpace) {
+		if i > 0 && part == "" {
+			continue
+		}
+		if i > 0 {
+			b.WriteString(ideographicSpace)
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// despaceCJK removes runs of ASCII spaces between two Han characters, as OCR inserts in 你 好 世 界.
+// A space next to any other character, such as in 你好 hello, is kept.
+func despaceCJK(text string) string {
+	if !strings.Contains(text, " ") {
+		return text
+	}
+	runes := []rune(text)
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != ' ' {
+			b.WriteRune(runes[i])
+			continue
+		}
+		end := i
+		for end < len(runes) && runes[end] == ' ' {
+			end++
+		}
+		if i == 0 || end == len(runes) || ScriptOf(runes[i-1]) != ScriptChinese || ScriptOf(runes[end]) != ScriptChinese {
+			b.WriteString(string(runes[i:end]))
+		}
+		i = end - 1
+	}
+	return b.String()
+}
+
+// mapPunctuation replaces the punctuation in text according to table. A comma or colon between
+// two ASCII digits, as in 1,000 or 10:30, is a number separator and is left alone.
+func mapPunctuation(text string, table map[rune]rune) string {
+	runes := []rune(text)
+	var b strings.Builder
+	for i, r := range runes {
+		if mapped, ok := table[r]; ok && !(isNumberSeparator(r) && betweenDigits(runes, i)) {
+			r = mapped
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isNumberSeparator reports whether r can separate digit groups or clock times.
+func isNumberSeparator(r rune) bool {
+	return r == ',' || r == ':' || r == '，' || r == '：'
+}
+
+// betweenDigits reports whether the rune at i sits between two ASCII digits.
+func betweenDigits(runes []rune, i int) bool {
+	isDigit := func(r rune) bool { return r >= '0' && r <= '9' }
+	return i > 0 && i < len(runes)-1 && isDigit(runes[i-1]) && isDigit(runes[i+1])
+}
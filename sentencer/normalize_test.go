@@ -0,0 +1,141 @@
+package sentencer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		configure func(o *Options)
+		want      []string
+	}{
+		{"canon punct", "你好,世界!", func(o *Options) { o.CanonPunct = true }, []string{"你好，世界！"}},
+		{"canon punct keeps number separators", "共1,000人,10:30到", func(o *Options) { o.CanonPunct = true }, []string{"共1,000人，10:30到"}},
+		{"canon punct skips english", "Hello,world", func(o *Options) { o.CanonPunct = true }, []string{"Hello,world"}},
+		{"canon punct en", "Hello（world）", func(o *Options) { o.CanonPunctEnglish = true }, []string{"Hello(world)"}},
+		{"straight quotes", "“hello’s”", func(o *Options) { o.StraightQuotes = true }, []string{`"hello's"`}},
+		{"straight quotes skip chinese", "“你好”", func(o *Options) { o.StraightQuotes = true }, []string{"“你好”"}},
+		{"despace cjk", "你 好 世  界", func(o *Options) { o.DespaceCJK = true }, []string{"你好世界"}},
+		{"despace cjk keeps latin spacing", "你好 hello 世界", func(o *Options) { o.DespaceCJK = true }, []string{"你好 hello 世界"}},
+		{"ideographic space", "　　床前　　明月光　", func(o *Options) { o.IdeographicSpace = true; o.Trim = TrimNone },
+			[]string{"床前　明月光"}},
+		{"ideographic space off", "　　床前　　明月光", func(o *Options) { o.Trim = TrimNone }, []string{"　　床前　　明月光"}},
+		{"strip invisible", "你\u200b好\u00ad世\ufeff界", func(o *Options) { o.StripInvisible = true }, []string{"你好世界"}},
+		{"strip invisible keeps emoji joiners", "👨\u200d👩\u200d👧", func(o *Options) { o.StripInvisible = true },
+			[]string{"👨\u200d👩\u200d👧"}},
+		{"strip dialogue", "——你来了？—我来了。你好——世界", func(o *Options) { o.StripDialogue = true },
+			[]string{"你来了？", "我来了。", "你好—", "—", "世界"}},
+		{"strip ranges", "文本【注：xxx】更多", func(o *Options) { o.StripRanges = "【】" }, []string{"文本更多"}},
+		{"strip nested ranges", "甲【注【内】外】乙〔删〕丙", func(o *Options) { o.StripRanges = "【】〔〕" }, []string{"甲乙丙"}},
+		{"strip ranges keeps unclosed", "甲【未闭合，乙", func(o *Options) { o.StripRanges = "【】" }, []string{"甲【未闭合，", "乙"}},
+		{"strip ranges keeps stray closer", "甲】乙【注】丙", func(o *Options) { o.StripRanges = "【】" }, []string{"甲】乙丙"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ProcessText(tt.input, withOptions(tt.configure)).Combined
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Combined = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripInvisibleThenDedup(t *testing.T) {
+	opts := withOptions(func(o *Options) { o.StripInvisible = true; o.Dedup = DedupExact })
+	got := ProcessText("你好\u200b世界\n你好世界\n\u200b你\u200c好世界", opts).Combined
+	if want := []string{"你好世界"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Combined = %q, want %q", got, want)
+	}
+}
+
+func TestUnifyDashes(t *testing.T) {
+	opts := withOptions(func(o *Options) { o.UnifyDashes = true })
+	for _, dash := range []rune(dashes) {
+		english := "well" + string(dash) + "known"
+		if got := ProcessText(english, opts).Combined; !reflect.DeepEqual(got, []string{"well-known"}) {
+			t.Errorf("English %q: got %q, want [well-known]", english, got)
+		}
+		chinese := "你好" + string(dash) + "世界"
+		if got := ProcessText(chinese, opts).Combined; !reflect.DeepEqual(got, []string{"你好——", "世界"}) {
+			t.Errorf("Chinese %q: got %q, want [你好—— 世界]", chinese, got)
+		}
+	}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"你好——世界", []string{"你好——", "世界"}},
+		{"你好--世界", []string{"你好——", "世界"}},
+		{"你好—－‐世界", []string{"你好——", "世界"}},
+		{"你好 — world", []string{"你好 ——", "world"}},
+		{"日期2021-01-01", []string{"日期2021-01-01"}},
+		{"Wi-Fi", []string{"Wi-Fi"}},
+		{"a — b", []string{"a - b"}},
+		{"a -- b", []string{"a -- b"}},
+	}
+	for _, tt := range tests {
+		if got := ProcessText(tt.input, opts).Combined; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ProcessText(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCollapseRepeatedMarks(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"真的吗？？？", "真的吗？"},
+		{"wow!!!", "wow!"},
+		{"好！？！", "好！？！"},
+		{"等等...", "等等..."},
+		{"好。。", "好。"},
+	}
+	for _, tt := range tests {
+		if got := collapseRepeatedMarks(tt.input); got != tt.want {
+			t.Errorf("collapseRepeatedMarks(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestIsDivider(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"—————", true},
+		{"  ＊＊＊  ", true},
+		{"===", true},
+		{"**", false},
+		{"*-*", false},
+		{"abc", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isDivider(tt.line); got != tt.want {
+			t.Errorf("isDivider(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestValidateRangePairs(t *testing.T) {
+	tests := []struct {
+		pairs   string
+		wantErr bool
+	}{
+		{"【】", false},
+		{"【】〔〕", false},
+		{"", true},
+		{"【", true},
+		{"【】【】", true},
+		{"( )", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateRangePairs(tt.pairs); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateRangePairs(%q) = %v, want error %v", tt.pairs, err, tt.wantErr)
+		}
+	}
+}
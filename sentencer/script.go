@@ -0,0 +1,245 @@
+package sentencer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Script is the coarse writing-system category a rune belongs to.
+type Script int
+
+const (
+	ScriptOther   Script = iota // Digits, punctuation, symbols and other scripts
+	ScriptChinese               // Han ideographs
+	ScriptEnglish               // ASCII Latin letters
+)
+
+// ScriptOf classifies r as Chinese, English or other.
+func ScriptOf(r rune) Script {
+	switch {
+	case unicode.Is(unicode.Han, r):
+		return ScriptChinese
+	case r < 0x80 && unicode.IsLetter(r):
+		return ScriptEnglish
+	default:
+		return ScriptOther
+	}
+}
+
+// DominantScript classifies a fragment: Chinese when it contains any Han character,
+// English when it contains ASCII letters but no Han character, and other otherwise.
+func DominantScript(text string) Script {
+	sawEnglish := false
+	for _, r := range text {
+		switch ScriptOf(r) {
+		case ScriptChinese:
+			return ScriptChinese
+		case ScriptEnglish:
+			sawEnglish = true
+		}
+	}
+	if sawEnglish {
+		return ScriptEnglish
+	}
+	return ScriptOther
+}
+
+// isBilingual reports whether text contains at least one Han character and one ASCII letter.
+func isBilingual(text string) bool {
+	sawChinese, sawEnglish := false, false
+	for _, r := range text {
+		switch ScriptOf(r) {
+		case ScriptChinese:
+			sawChinese = true
+		case ScriptEnglish:
+			sawEnglish = true
+		}
+		if sawChinese && sawEnglish {
+			return true
+		}
+	}
+	return false
+}
+
+// Digit classification policies for Options.Digits.
+const (
+	DigitsNeutral = "neutral" // Digits belong to neither script (the default, matching earlier releases)
+	DigitsChinese = "chinese" // Digits count as Chinese
+	DigitsEnglish = "english" // Digits count as English
+)
+
+// Policies for Options.AlnumTokens, deciding how the digits of a token mixing ASCII letters and
+// digits such as COVID19, Web3 or GB2312 are classified.
+const (
+	AlnumKeep  = "keep"  // The digits count as English, so the token is never cut apart (the default)
+	AlnumSplit = "split" // The digits follow Options.Digits like any other digit
+)
+
+// Policies for Options.Overlap, deciding where a fragment mixing Chinese and English goes.
+const (
+	OverlapBoth  = "both"  // Keep it whole; it counts as Chinese and also appears in Bilingual (the default)
+	OverlapSplit = "split" // Cut it where the script changes, as with Options.ScriptSplit
+	OverlapDrop  = "drop"  // Keep it whole in the combined stream; its pieces of each script go to that script's stream
+)
+
+// digitScript returns the script digits are assigned to under the given policy.
+func digitScript(policy string) Script {
+	switch policy {
+	case DigitsChinese:
+		return ScriptChinese
+	case DigitsEnglish:
+		return ScriptEnglish
+	default:
+		return ScriptOther
+	}
+}
+
+// scriptOf classifies r like ScriptOf, assigning digits according to the digit policy.
+func (o Options) scriptOf(r rune) Script {
+	if unicode.IsDigit(r) {
+		return digitScript(o.Digits)
+	}
+	return ScriptOf(r)
+}
+
+// Classify routes a fragment like DominantScript, except that a fragment whose only
+// script-bearing characters are digits follows the digit policy.
+func (o Options) Classify(text string) Script {
+	if sc := DominantScript(text); sc != ScriptOther {
+		return sc
+	}
+	if strings.IndexFunc(text, unicode.IsDigit) >= 0 {
+		return digitScript(o.Digits)
+	}
+	return ScriptOther
+}
+
+// splitOnScriptChange cuts text wherever it switches between Chinese and English, so 你好hello世界
+// becomes 你好, hello and 世界. Spaces, punctuation and, under the neutral digit policy, digits
+// belong to neither script and stay with the run they follow. Unless Options.AlnumTokens is
+// AlnumSplit, the digits of a letters-and-digits token such as COVID19 count as English.
+func (o Options) splitOnScriptChange(text string) []string {
+	var tokenDigits map[int]bool
+	if o.AlnumTokens != AlnumSplit {
+		tokenDigits = alnumTokenDigits(text)
+	}
+	var parts []string
+	start := 0
+	current := ScriptOther
+	for i, r := range text {
+		sc := o.scriptOf(r)
+		if tokenDigits[i] {
+			sc = ScriptEnglish
+		}
+		if sc == ScriptOther {
+			continue
+		}
+		if current != ScriptOther && sc != current {
+			parts = append(parts, text[start:i])
+			start = i
+		}
+		current = sc
+	}
+	return append(parts, text[start:])
+}
+
+// alnumTokenDigits returns the byte offsets of the digits in text that belong to a run of ASCII
+// letters and digits holding at least one letter, such as the 19 of COVID19.
+func alnumTokenDigits(text string) map[int]bool {
+	var digits map[int]bool
+	start, hasLetter := -1, false
+	flush := func(end int) {
+		if start >= 0 && hasLetter {
+			for i := start; i < end; i++ {
+				if isASCIIDigit(text[i]) {
+					if digits == nil {
+						digits = map[int]bool{}
+					}
+					digits[i] = true
+				}
+			}
+		}
+		start, hasLetter = -1, false
+	}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		isLetter := c < 0x80 && unicode.IsLetter(rune(c))
+		if !isLetter && !isASCIIDigit(c) {
+			flush(i)
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		hasLetter = hasLetter || isLetter
+	}
+	flush(len(text))
+	return digits
+}
+
+// isASCIIDigit reports whether c is one of the ASCII digits 0-9.
+func isASCIIDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Languages for Options.Target.
+const (
+	TargetChinese = "zh" // Keep only fragments that are predominantly Chinese
+	TargetEnglish = "en" // Keep only fragments that are predominantly English
+)
+
+// PredominantScript classifies a fragment by the script carrying most of its content: Chinese when
+// it has more Han characters than English words (runs of ASCII letters), English when it has more
+// English words than Han characters, and other on a tie. A Han character is weighed like a word, so
+// 我用iPhone is Chinese while Hello world 你 is English.
+func PredominantScript(text string) Script {
+	han, words := 0, 0
+	inWord := false
+	for _, r := range text {
+		sc := ScriptOf(r)
+		if sc == ScriptChinese {
+			han++
+		}
+		if sc == ScriptEnglish && !inWord {
+			words++
+		}
+		inWord = sc == ScriptEnglish
+	}
+	switch {
+	case han > words:
+		return ScriptChinese
+	case words > han:
+		return ScriptEnglish
+	default:
+		return ScriptOther
+	}
+}
+
+// Language hints for Options.LangHint.
+const (
+	LangHintAuto    = "auto" // Handle both languages (the default)
+	LangHintChinese = "zh"   // The text is Chinese: skip the English stages and keep only Chinese fragments
+	LangHintEnglish = "en"   // The text is English: keep only English fragments
+)
+
+// hintedSentences keeps the sentences Classify routes to script, returning the kept and the
+// dropped sentences.
+func (o Options) hintedSentences(sentences []Sentence, script Script) (kept, dropped []Sentence) {
+	kept = sentences[:0]
+	for _, s := range sentences {
+		if o.Classify(s.Text) == script {
+			kept = append(kept, s)
+		} else {
+			dropped = append(dropped, s)
+		}
+	}
+	return kept, dropped
+}
+
+// targetScript returns the script of a Target language.
+func targetScript(target string) Script {
+	if target == TargetEnglish {
+		return ScriptEnglish
+	}
+	return ScriptChinese
+}
@@ -0,0 +1,92 @@
+package sentencer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		text   string
+		digits string
+		want   Script
+	}{
+		{"你好", DigitsNeutral, ScriptChinese},
+		{"hello", DigitsNeutral, ScriptEnglish},
+		{"你好world", DigitsNeutral, ScriptChinese},
+		{"12345", DigitsNeutral, ScriptOther},
+		{"12345", DigitsChinese, ScriptChinese},
+		{"12345", DigitsEnglish, ScriptEnglish},
+		{"１２３", DigitsChinese, ScriptChinese},
+		{"3 apples", DigitsChinese, ScriptEnglish},
+		{"……", DigitsChinese, ScriptOther},
+	}
+	for _, tt := range tests {
+		opts := Options{Digits: tt.digits}
+		if got := opts.Classify(tt.text); got != tt.want {
+			t.Errorf("Classify(%q) under %s = %v, want %v", tt.text, tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestSplitOnScriptChange(t *testing.T) {
+	tests := []struct {
+		text, digits, alnum string
+		want                []string
+	}{
+		{"你好hello世界", DigitsNeutral, AlnumKeep, []string{"你好", "hello", "世界"}},
+		{"你好, hello世界", DigitsNeutral, AlnumKeep, []string{"你好, ", "hello", "世界"}},
+		{"共3个apple", DigitsNeutral, AlnumKeep, []string{"共3个", "apple"}},
+		{"apple3个", DigitsNeutral, AlnumKeep, []string{"apple3", "个"}},
+		{"apple 3个", DigitsChinese, AlnumKeep, []string{"apple ", "3个"}},
+		{"共3个apple", DigitsEnglish, AlnumKeep, []string{"共", "3", "个", "apple"}},
+		{"感染COVID19病毒", DigitsChinese, AlnumKeep, []string{"感染", "COVID19", "病毒"}},
+		{"编码GB2312字符", DigitsChinese, AlnumKeep, []string{"编码", "GB2312", "字符"}},
+		{"感染COVID19病毒", DigitsChinese, AlnumSplit, []string{"感染", "COVID", "19病毒"}},
+	}
+	for _, tt := range tests {
+		opts := Options{Digits: tt.digits, AlnumTokens: tt.alnum}
+		if got := opts.splitOnScriptChange(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitOnScriptChange(%q) under %s/%s = %q, want %q", tt.text, tt.digits, tt.alnum, got, tt.want)
+		}
+	}
+}
+
+func TestPredominantScript(t *testing.T) {
+	tests := []struct {
+		text string
+		want Script
+	}{
+		{"我用iPhone拍照", ScriptChinese},
+		{"Download the new 文件", ScriptEnglish},
+		{"Hello world 你", ScriptEnglish},
+		{"Download the 文件", ScriptOther},
+		{"42", ScriptOther},
+	}
+	for _, tt := range tests {
+		if got := PredominantScript(tt.text); got != tt.want {
+			t.Errorf("PredominantScript(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDigitOnlyLineRouting(t *testing.T) {
+	tests := []struct {
+		digits                   string
+		wantChinese, wantEnglish int
+	}{
+		{DigitsNeutral, 0, 0},
+		{DigitsChinese, 1, 0},
+		{DigitsEnglish, 0, 1},
+	}
+	for _, tt := range tests {
+		result := ProcessText("2021", withOptions(func(o *Options) { o.Digits = tt.digits }))
+		if len(result.Chinese) != tt.wantChinese || len(result.English) != tt.wantEnglish {
+			t.Errorf("-digits %s: Chinese %q, English %q, want %d and %d sentences",
+				tt.digits, result.Chinese, result.English, tt.wantChinese, tt.wantEnglish)
+		}
+		if len(result.Combined) != 1 {
+			t.Errorf("-digits %s: Combined = %q, want the digit line", tt.digits, result.Combined)
+		}
+	}
+}
@@ -0,0 +1,1048 @@
+/*
+Package sentencer splits text into sentence fragments after Chinese punctuation marks and
+removes empty fragments. It works on in-memory text only and never touches files or flags;
+the txt-sentencers_cn command is built on top of it.
+
+Example:
+
+	result := sentencer.ProcessText("你好，世界。Hello there", sentencer.DefaultOptions())
+	// result.Combined: ["你好，", "世界。", "Hello there"]
+	// result.Chinese:  ["你好，", "世界。"]
+	// result.English:  ["Hello there"]
+	// result.Stats:    {Lines: 1, Sentences: 3, ChineseSentences: 2, EnglishSentences: 1}
+
+Splitting can be tuned through Options, e.g. to keep lists on one line:
+
+	opts := sentencer.DefaultOptions()
+	opts.SplitEnum = false
+	result = sentencer.ProcessText("苹果、香蕉、橙子", opts) // result.Combined: ["苹果、香蕉、橙子"]
+
+WriteStreams writes the streams of a Result to any io.Writer, e.g. buffers or network connections:
+
+	var zh, en bytes.Buffer
+	err := sentencer.WriteStreams(sentencer.WriterSet{Chinese: &zh, English: &en}, result, opts)
+*/
+package sentencer
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chinese punctuation marks after which a fragment ends, followed by their vertical presentation
+// forms (U+FE10–U+FE19) found in text converted from vertical layout. The enumeration comma 、
+// separates list items rather than clauses, so it is kept apart to be toggled on its own.
+const (
+	clausePunctuation = "，。？：！；…—" + "︐︒︓︔︕︖︙"
+	enumerationComma  = "、︑"
+)
+
+// Modes for Options.Dedup.
+const (
+	DedupExact      = "exact"      // Sentences are duplicates when their texts are identical
+	DedupNormalized = "normalized" // Sentences are duplicates when their dedupKey is identical
+)
+
+// Colon handling modes for Options.Colon.
+const (
+	ColonHard = "hard" // The colon ends a fragment like any other mark (the default)
+	ColonSoft = "soft" // A fragment ending in a colon runs on to the next terminal mark; see mergeColonClauses
+	ColonNone = "none" // The colon is not a boundary at all
+)
+
+// colons are the full-width colon and its vertical form in the default punctuation marks.
+const colons = "：︓"
+
+// Trimming modes for Options.Trim.
+const (
+	TrimBoth  = "both"  // Trim leading and trailing whitespace (the default)
+	TrimRight = "right" // Trim trailing whitespace only, keeping indentation
+	TrimNone  = "none"  // Keep fragments as split
+)
+
+// DefaultMaxFragments caps the fragments of one input line far above anything normal text produces,
+// guarding memory against malformed input such as a line of thousands of commas.
+const DefaultMaxFragments = 10000
+
+// Options controls how input text is split into sentences.
+type Options struct {
+	SplitEnum        bool   // Treat the enumeration comma 、 as a boundary
+	Terminators      string // When set, replaces the default punctuation marks entirely; see ValidateTerminators
+	KeepUnits        bool   // Re-attach numbers split from their following measure unit
+	ScriptSplit      bool   // Also split where a fragment switches between Chinese and English
+	EnglishTokenizer bool   // Also split English text into sentences by rule; see splitEnglishSentences
+	CollapsePunct    bool   // Fold runs of an identical terminal mark (？？？, !!!) into one before splitting
+	WholeFile        bool   // Treat the text as one stream, ignoring line boundaries; see joinLines
+	KeepDividers     bool   // Emit each divider line such as ————— as the marker ---; see isDivider
+	Digits           string // How digits are classified: DigitsNeutral (default), DigitsChinese or DigitsEnglish
+	AlnumTokens      string // How the digits of tokens such as COVID19 are classified: AlnumKeep (default) or AlnumSplit
+	Overlap          string // Where mixed Chinese and English fragments go: OverlapBoth (default), OverlapSplit or OverlapDrop
+	Trim             string // Which whitespace is trimmed from fragments: TrimBoth (default), TrimRight or TrimNone
+	Colon            string // How the default colon marks split: ColonHard (default), ColonSoft or ColonNone
+	NoZhSemicolon    bool   // Do not split at the full-width semicolon ； of the default marks
+	EnSemicolon      bool   // Also split English text after a semicolon followed by whitespace; see cutAfterEnglishSemicolons
+
+	// SplitTableGlyphs treats the table separators in tableGlyphs as cell boundaries and drops them,
+	// instead of keeping them as part of the text.
+	SplitTableGlyphs bool
+
+	MaxFragments int // Cap on fragments per input line; 0 means unlimited
+
+	// CanonPunct maps ASCII punctuation in Chinese fragments to full-width forms (你好,世界 becomes
+	// 你好，世界); CanonPunctEnglish maps full-width punctuation in English fragments to ASCII.
+	CanonPunct        bool
+	CanonPunctEnglish bool
+
+	// UnifyDashes maps the dash and hyphen variants in dashes to one form before splitting: the
+	// Chinese dash —— next to Han characters, split after as one mark, and the ASCII hyphen-minus
+	// elsewhere; see unifyDashes.
+	UnifyDashes bool
+
+	// DigitForm, when set to DigitFormASCII, DigitFormFullWidth or DigitFormHanzi, rewrites the ASCII
+	// digits, full-width digits and basic Chinese numerals of every fragment in that one form; see
+	// convertDigits and hanziNumber for what is read. Empty keeps digits as they are.
+	DigitForm string
+
+	// StraightQuotes maps curly quotes and apostrophes in English fragments (“hello’s” becomes
+	// "hello's"); quotes in Chinese fragments are kept.
+	StraightQuotes bool
+
+	// StripRanges, when set, removes every span enclosed by one of its delimiter pairs, such as the
+	// editorial annotation 【注：...】 with 【】, from every line before splitting; see stripRanges and
+	// ValidateRangePairs.
+	StripRanges string
+
+	// StripInvisible removes soft hyphens, zero-width spaces and the other invisible format
+	// characters in invisibleChars from every line before splitting, keeping the zero-width
+	// joiners inside emoji; see stripInvisible.
+	StripInvisible bool
+
+	// StripDialogue removes the dialogue dash — or —— starting a sentence, as in ——你来了; see
+	// stripDialogueDashes.
+	StripDialogue bool
+
+	// IdeographicSpace folds runs of the full-width space U+3000 inside a fragment into one and
+	// removes it from both ends even when Trim keeps other whitespace.
+	IdeographicSpace bool
+
+	// DespaceCJK removes the spaces OCR inserts between Han characters; see despaceCJK.
+	DespaceCJK bool
+
+	// CombinedSep, when set, joins adjacent Chinese and English fragments of one input line
+	// in the combined text stream written by WriteStreams.
+	CombinedSep string
+
+	// InlineTags prefixes every fragment of the combined text stream with its language tag, as in
+	// [zh]你好 or [en]hello; see InlineTag.
+	InlineTags bool
+
+	// CombinedMode lays out the combined text stream: CombinedModeLines (the default, also for "")
+	// writes one fragment per line, CombinedModeBlocks groups consecutive fragments of one
+	// language under a labeled header line; see Blocks.
+	CombinedMode string
+
+	// Blocklist drops every input line matching any of its patterns before splitting, e.g. page
+	// numbers or copyright notices. The lines still count for line numbers and Stats.Lines.
+	Blocklist []*regexp.Regexp
+
+	// FromLine and ToLine restrict processing to the 1-based input lines FromLine through ToLine,
+	// inclusive, e.g. to debug one region of a huge file; 0 leaves that end open. Sentences keep
+	// their real line numbers, and the lines before FromLine still count for Stats.Lines. Reading
+	// stops after ToLine, so the lines after it are neither read from files nor counted.
+	FromLine, ToLine int
+
+	// CollapseAdjacent keeps only the first of consecutive identical sentences, as OCR output
+	// repeating a line produces; identical sentences elsewhere are kept.
+	CollapseAdjacent bool
+
+	// Dedup keeps only the first occurrence of each sentence in the text: DedupExact compares the
+	// texts as they are, DedupNormalized by their dedupKey, so Hello. and hello are duplicates.
+	// Empty disables it.
+	Dedup string
+
+	// DedupCache, when positive, bounds the set of sentences Dedup remembers to the DedupCache most
+	// recently seen ones, bounding memory on giant inputs: a repeat is only caught while its
+	// earlier occurrence is still remembered, so deduplication becomes approximate.
+	DedupCache int
+
+	// DedupWindow, when positive, makes Dedup local: a sentence is only dropped when it already
+	// occurred on its own line or one of the DedupWindow lines before it, so repeats close together,
+	// such as a refrain, go while distant ones stay. It takes precedence over DedupCache.
+	DedupWindow int
+
+	// Exclude drops every sentence whose text is in the set, e.g. the sentences of an existing
+	// corpus, so only new ones are kept.
+	Exclude map[string]bool
+
+	// MaxPunctRatio, when positive, drops every fragment in which punctuation (\p{P}) makes up more
+	// than this fraction of the runes, as garbled text such as ，，。、！ does.
+	MaxPunctRatio float64
+
+	// RequireBalanced drops every fragment whose brackets and quotes in bracketPairs do not pair up
+	// and nest properly, a common sign of a bad split.
+	RequireBalanced bool
+
+	// LangHint declares the language of a mono-language text: LangHintChinese skips the English-only
+	// splitting stages (EnglishTokenizer, EnSemicolon) and, like LangHintEnglish, keeps only the
+	// fragments Classify routes to that language, so the other stream stays empty. Empty or
+	// LangHintAuto handles both languages.
+	LangHint string
+
+	// Target, when set to TargetChinese or TargetEnglish, keeps only the fragments whose
+	// PredominantScript is that language, dropping the other language, mixed fragments dominated
+	// by it and fragments of neither script.
+	Target string
+
+	// Reverse reverses the order of the cleaned sentences, and so of every stream, putting the
+	// last sentence of the text first.
+	Reverse bool
+
+	// Grep, when set, keeps only the cleaned fragments it matches, or with GrepInvert only
+	// those it does not match.
+	Grep       *regexp.Regexp
+	GrepInvert bool
+
+	// ReportEmptyLines records in Stats.EmptyLines the non-blank input lines that produced no
+	// fragment. It has no effect with WholeFile, where a sentence may span lines.
+	ReportEmptyLines bool
+
+	// KeepOriginal sets Sentence.Original on every sentence.
+	KeepOriginal bool
+
+	// KeepRejected records the sentences dropped by Grep, CollapseAdjacent, Dedup, Exclude, MaxPunctRatio,
+	// RequireBalanced, LangHint, Target and StripBoilerplate in Result.Rejected, to check that the filters are not throwing away good data.
+	KeepRejected bool
+
+	// Workers bounds how many files ProcessFiles handles at once, and Open replaces
+	// os.Open for reading them, e.g. to serve in-memory fixtures.
+	Workers int
+	Open    func(path string) (io.ReadCloser, error)
+}
+
+// DefaultOptions returns the options matching the command's default behavior.
+func DefaultOptions() Options {
+	return Options{SplitEnum: true, MaxFragments: DefaultMaxFragments}
+}
+
+// punctuationRegex compiles the regex capturing the punctuation marks that end a fragment.
+func (o Options) punctuationRegex() *regexp.Regexp {
+	marks := o.Terminators
+	if marks == "" {
+		marks = clausePunctuation
+		if o.SplitEnum {
+			marks += enumerationComma
+		}
+		if o.Colon == ColonNone {
+			marks = strings.NewReplacer("：", "", "︓", "").Replace(marks)
+		}
+		if o.NoZhSemicolon {
+			marks = strings.NewReplacer("；", "", "︔", "").Replace(marks)
+		}
+	}
+	pattern := characterClass(marks)
+	if o.UnifyDashes && strings.ContainsRune(marks, '—') {
+		pattern = chineseDash + "|" + pattern // A unified Chinese dash is one mark, not two
+	}
+	return regexp.MustCompile("(" + pattern + ")")
+}
+
+// characterClass builds a regex character class matching any rune of marks, escaping every
+// rune that is special inside a class so user-supplied sets cannot alter the pattern.
+func characterClass(marks string) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for _, r := range marks {
+		switch r {
+		case '\\', ']', '[', '^', '-':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// ValidateTerminators checks an Options.Terminators value: it must be non-empty valid UTF-8
+// and must not contain whitespace or control characters, which would break line handling.
+func ValidateTerminators(marks string) error {
+	if marks == "" {
+		return fmt.Errorf("terminator set is empty")
+	}
+	if !utf8.ValidString(marks) {
+		return fmt.Errorf("terminator set is not valid UTF-8")
+	}
+	for _, r := range marks {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return fmt.Errorf("terminator set contains whitespace or control character %q", r)
+		}
+	}
+	return nil
+}
+
+// ValidateRangePairs checks an Options.StripRanges value: a non-empty, valid UTF-8 sequence of
+// opening and closing runes, e.g. 【】〔〕, in which no rune appears twice.
+func ValidateRangePairs(pairs string) error {
+	if !utf8.ValidString(pairs) {
+		return fmt.Errorf("delimiter pairs are not valid UTF-8")
+	}
+	delimiters := []rune(pairs)
+	if len(delimiters) == 0 || len(delimiters)%2 != 0 {
+		return fmt.Errorf("delimiter pairs %q must be an opening and a closing character per pair", pairs)
+	}
+	seen := map[rune]bool{}
+	for _, r := range delimiters {
+		if seen[r] {
+			return fmt.Errorf("delimiter %q appears more than once", r)
+		}
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return fmt.Errorf("delimiter pairs contain whitespace or control character %q", r)
+		}
+		seen[r] = true
+	}
+	return nil
+}
+
+// Sentence is a single cleaned fragment together with the 1-based input line it came from.
+type Sentence struct {
+	Text       string `json:"text"`
+	SourceLine int    `json:"source_line"`
+
+	// Matcher names the splitting stage that ended the fragment, one of the Matcher* constants,
+	// to debug why a fragment was cut where it was.
+	Matcher string `json:"-"`
+
+	// Original is the fragment as it was cut from the line, before trimming and normalization,
+	// to audit what cleaning changed. It is only set with Options.KeepOriginal.
+	Original string `json:"-"`
+}
+
+// Splitting stages reported in Sentence.Matcher.
+const (
+	MatcherPunctuation      = "punctuation"       // A mark of the terminator set
+	MatcherEnd              = "end"               // The end of the line, or of the text with Options.WholeFile
+	MatcherScriptSplit      = "script-split"      // A switch between Chinese and English (Options.ScriptSplit)
+	MatcherEnglishTokenizer = "english-tokenizer" // An English sentence end (Options.EnglishTokenizer)
+	MatcherTableGlyph       = "table-glyph"       // A table separator (Options.SplitTableGlyphs)
+	MatcherLineBreak        = "line-break"        // A line break left inside a fragment by an earlier stage
+	MatcherDivider          = "divider"           // A divider line kept as a marker (Options.KeepDividers)
+)
+
+// piece is a fragment being split, with the Matcher of the stage that ended it.
+type piece struct {
+	text, matcher string
+}
+
+// cutPieces cuts every piece with cut. Each part but the last was ended by the stage named matcher;
+// the last part ends where the whole piece did.
+func cutPieces(pieces []piece, matcher string, cut func(string) []string) []piece {
+	var out []piece
+	for _, p := range pieces {
+		parts := cut(p.text)
+		for i, part := range parts {
+			if i == len(parts)-1 {
+				out = append(out, piece{text: part, matcher: p.matcher})
+			} else {
+				out = append(out, piece{text: part, matcher: matcher})
+			}
+		}
+	}
+	return out
+}
+
+// Stats summarizes one ProcessText run.
+type Stats struct {
+	Lines            int // Input lines read
+	Sentences        int // Fragments kept after cleaning
+	ChineseSentences int // Fragments containing at least one Han character; with OverlapDrop, Chinese pieces
+	EnglishSentences int // Fragments containing ASCII letters but no Han character, or English pieces
+
+	// CappedLines lists the input lines whose remainder was kept as one fragment
+	// because they exceeded Options.MaxFragments.
+	CappedLines []int
+
+	// EmptyLines lists the non-blank input lines that produced no fragment, before Grep filtering,
+	// when Options.ReportEmptyLines is set.
+	EmptyLines []EmptyLine
+
+	Timings Timings
+}
+
+// emptyLineSnippetRunes is how much of a line an EmptyLine keeps.
+const emptyLineSnippetRunes = 40
+
+// EmptyLine is a non-blank input line that produced no fragment, such as a line whose only
+// content was removed by cleaning.
+type EmptyLine struct {
+	Line    int    // 1-based line number
+	Snippet string // The trimmed start of the line, at most 40 runes
+}
+
+// Timings records how long each pipeline stage took. Read is only set by ProcessFiles.
+type Timings struct {
+	Read  time.Duration // Reading the input
+	Split time.Duration // Scanning lines and cutting them into trimmed fragments
+	Clean time.Duration // Merging, normalizing, filtering and classifying the fragments
+}
+
+// Result is the outcome of ProcessText.
+type Result struct {
+	// Sentences holds every fragment in input order with its source line.
+	Sentences []Sentence
+
+	// Combined holds the text of every fragment in input order. Chinese holds the fragments
+	// containing at least one Han character and English those containing ASCII letters but no
+	// Han character. Digit-only fragments follow Options.Digits; fragments with neither script
+	// only appear in Combined.
+	Chinese, English, Combined []string
+
+	// Bilingual holds the fragments containing both a Han character and an ASCII letter.
+	// They also appear in Chinese.
+	Bilingual []string
+
+	// Numeric holds the fragments dominated by a number, such as 3.5kg or 2021年, which have
+	// no more letters than digits. They also appear in the other streams.
+	Numeric []string
+
+	// Rejected holds the sentences dropped by a filter, in the order they were dropped, when
+	// Options.KeepRejected is set.
+	Rejected []Rejection
+
+	Stats Stats
+}
+
+// Filters reported in Rejection.Filter.
+const (
+	RejectedGrep        = "grep"               // Options.Grep did not match, or matched with GrepInvert
+	RejectedAdjacent    = "adjacent-duplicate" // Options.CollapseAdjacent: same text as the sentence before
+	RejectedDuplicate   = "duplicate"          // Options.Dedup: same text as an earlier sentence
+	RejectedExisting    = "existing"           // Options.Exclude: already in the excluded set
+	RejectedPunctuation = "punctuation"        // Options.MaxPunctRatio: too much punctuation
+	RejectedUnbalanced  = "unbalanced"         // Options.RequireBalanced: unpaired brackets or quotes
+	RejectedLangHint    = "lang-hint"          // Options.LangHint: not in the hinted language
+	RejectedTarget      = "target"             // Options.Target: not predominantly the target language
+	RejectedBoilerplate = "boilerplate"        // StripBoilerplate: the line repeats across most files
+)
+
+// Rejection is a sentence dropped by a filter, with the filter that dropped it.
+type Rejection struct {
+	Sentence
+	Filter string
+}
+
+// reject records the sentences dropped by filter when opts.KeepRejected is set.
+func (r *Result) reject(dropped []Sentence, filter string, opts Options) {
+	if !opts.KeepRejected {
+		return
+	}
+	for _, s := range dropped {
+		r.Rejected = append(r.Rejected, Rejection{Sentence: s, Filter: filter})
+	}
+}
+
+// ProcessText splits every line of text after each punctuation mark and returns the
+// non-empty, trimmed fragments.
+func ProcessText(text string, opts Options) Result {
+	result, _ := ProcessTextContext(context.Background(), text, opts)
+	return result
+}
+
+// ProcessTextContext is ProcessText stopping to scan lines once ctx ends. The lines handled until
+// then are still cleaned and returned, together with ctx's error; Stats.Lines counts only them.
+// With Options.WholeFile the text is one stream, so ctx is only checked before it is split.
+func ProcessTextContext(ctx context.Context, text string, opts Options) (Result, error) {
+	splitStart := time.Now()
+	punctuationRegex := opts.punctuationRegex()
+	result := Result{Sentences: []Sentence{}}
+
+	// splitSegment cuts a segment of text after each punctuation mark and keeps its non-empty
+	// fragments; lineAt maps a byte offset in the segment to the input line it came from
+	splitSegment := func(segment string, lineAt func(offset int) int) {
+		fragments, capped := splitAfterPunctuation(segment, punctuationRegex, opts.MaxFragments)
+		if capped {
+			rest := fragments[len(fragments)-1]
+			result.Stats.CappedLines = append(result.Stats.CappedLines, lineAt(len(segment)-len(rest)))
+		}
+		if opts.Colon == ColonSoft {
+			fragments = mergeColonClauses(fragments)
+		}
+		pieces := make([]piece, len(fragments))
+		for i, fragment := range fragments {
+			pieces[i] = piece{text: fragment, matcher: MatcherPunctuation}
+		}
+		pieces[len(pieces)-1].matcher = MatcherEnd
+		if opts.ScriptSplit || opts.Overlap == OverlapSplit {
+			pieces = cutPieces(pieces, MatcherScriptSplit, opts.splitOnScriptChange)
+		}
+		if opts.EnglishTokenizer && opts.LangHint != LangHintChinese {
+			pieces = cutPieces(pieces, MatcherEnglishTokenizer, splitEnglishSentences)
+		}
+		if opts.EnSemicolon && opts.LangHint != LangHintChinese {
+			pieces = cutPieces(pieces, MatcherPunctuation, cutAfterEnglishSemicolons)
+		}
+		if opts.SplitTableGlyphs {
+			pieces = cutPieces(pieces, MatcherTableGlyph, cutAfterTableGlyphs)
+		}
+
+		// Remove empty fragments from the processed segment
+		offset := 0
+		for _, p := range pieces {
+			text := p.text
+			if opts.SplitTableGlyphs {
+				text = trimTableGlyph(text)
+			}
+			if strings.TrimSpace(text) != "" { // Exclude empty fragments
+				leading := len(text) - len(strings.TrimLeftFunc(text, unicode.IsSpace))
+				sentence := Sentence{Text: opts.trim(text), SourceLine: lineAt(offset + leading), Matcher: p.matcher}
+				if opts.KeepOriginal {
+					sentence.Original = p.text
+				}
+				result.Sentences = append(result.Sentences, sentence)
+			}
+			offset += len(p.text)
+		}
+	}
+
+	lines := scanTextLines(text, opts.ToLine)
+	result.Stats.Lines = len(lines)
+	limitLines(lines, opts.FromLine)
+	blockLines(lines, opts.Blocklist)
+	var err error
+	done := ctx.Done()
+	if opts.WholeFile {
+		if err = ctx.Err(); err == nil {
+			joined, lineAt := opts.joinLines(lines)
+			splitSegment(joined, lineAt)
+		} else {
+			result.Stats.Lines = 0
+		}
+	} else {
+	scan:
+		for i, line := range lines {
+			select {
+			case <-done:
+				err = ctx.Err()
+				result.Stats.Lines = i
+				break scan
+			default:
+			}
+			lineNumber := i + 1
+			if opts.KeepDividers && isDivider(line) {
+				result.Sentences = append(result.Sentences, Sentence{Text: dividerMarker, SourceLine: lineNumber, Matcher: MatcherDivider})
+				continue
+			}
+			splitSegment(opts.preprocessLine(line), func(int) int { return lineNumber })
+		}
+	}
+	result.Stats.Timings.Split = time.Since(splitStart)
+
+	cleanStart := time.Now()
+	if opts.KeepUnits {
+		result.Sentences = attachUnits(result.Sentences)
+	}
+	for i := range result.Sentences {
+		result.Sentences[i].Text = opts.normalize(result.Sentences[i].Text)
+	}
+	result.Sentences = opts.splitEmbeddedLineBreaks(result.Sentences)
+	var dropped []Sentence
+	if opts.CollapseAdjacent {
+		result.Sentences, dropped = collapseAdjacent(result.Sentences)
+		result.reject(dropped, RejectedAdjacent, opts)
+	}
+	if opts.ReportEmptyLines && !opts.WholeFile {
+		result.Stats.EmptyLines = emptyLines(lines[:result.Stats.Lines], result.Sentences)
+	}
+	if opts.Dedup != "" {
+		if opts.DedupWindow > 0 {
+			result.Sentences, dropped = dedupWindowSentences(result.Sentences, opts.Dedup, opts.DedupWindow)
+		} else {
+			result.Sentences, dropped = dedupSentences(result.Sentences, opts.Dedup, opts.DedupCache)
+		}
+		result.reject(dropped, RejectedDuplicate, opts)
+	}
+	if len(opts.Exclude) > 0 {
+		result.Sentences, dropped = excludeSentences(result.Sentences, opts.Exclude)
+		result.reject(dropped, RejectedExisting, opts)
+	}
+	if opts.MaxPunctRatio > 0 {
+		result.Sentences, dropped = punctuationSentences(result.Sentences, opts.MaxPunctRatio)
+		result.reject(dropped, RejectedPunctuation, opts)
+	}
+	if opts.RequireBalanced {
+		result.Sentences, dropped = balancedSentences(result.Sentences)
+		result.reject(dropped, RejectedUnbalanced, opts)
+	}
+	switch opts.LangHint {
+	case LangHintChinese:
+		result.Sentences, dropped = opts.hintedSentences(result.Sentences, ScriptChinese)
+		result.reject(dropped, RejectedLangHint, opts)
+	case LangHintEnglish:
+		result.Sentences, dropped = opts.hintedSentences(result.Sentences, ScriptEnglish)
+		result.reject(dropped, RejectedLangHint, opts)
+	}
+	if opts.Target != "" {
+		result.Sentences, dropped = targetSentences(result.Sentences, targetScript(opts.Target))
+		result.reject(dropped, RejectedTarget, opts)
+	}
+	if opts.Grep != nil {
+		result.Sentences, dropped = grepSentences(result.Sentences, opts.Grep, opts.GrepInvert)
+		result.reject(dropped, RejectedGrep, opts)
+	}
+	if opts.Reverse {
+		reverseSentences(result.Sentences)
+	}
+
+	opts.buildStreams(&result)
+	result.Stats.Timings.Clean = time.Since(cleanStart)
+	return result, err
+}
+
+// buildStreams fills the streams and their counts from result.Sentences.
+func (o Options) buildStreams(result *Result) {
+	result.Combined, result.Chinese, result.English, result.Bilingual, result.Numeric = nil, nil, nil, nil, nil
+	for _, s := range result.Sentences {
+		result.Combined = append(result.Combined, s.Text)
+		if isNumeric(s.Text) {
+			result.Numeric = append(result.Numeric, s.Text)
+		}
+		if isBilingual(s.Text) {
+			result.Bilingual = append(result.Bilingual, s.Text)
+			if o.Overlap == OverlapDrop {
+				// Each piece goes to the stream of its script, so no character is in both
+				for _, piece := range o.splitOnScriptChange(s.Text) {
+					if piece = strings.TrimSpace(piece); piece == "" {
+						continue
+					}
+					switch o.Classify(piece) {
+					case ScriptChinese:
+						result.Chinese = append(result.Chinese, piece)
+					case ScriptEnglish:
+						result.English = append(result.English, piece)
+					}
+				}
+				continue
+			}
+		}
+		switch o.Classify(s.Text) {
+		case ScriptChinese:
+			result.Chinese = append(result.Chinese, s.Text)
+		case ScriptEnglish:
+			result.English = append(result.English, s.Text)
+		}
+	}
+	result.Stats.Sentences = len(result.Combined)
+	result.Stats.ChineseSentences = len(result.Chinese)
+	result.Stats.EnglishSentences = len(result.English)
+}
+
+// trim removes the whitespace around a fragment selected by the trimming mode.
+func (o Options) trim(fragment string) string {
+	switch o.Trim {
+	case TrimRight:
+		return strings.TrimRightFunc(fragment, unicode.IsSpace)
+	case TrimNone:
+		return fragment
+	default:
+		return strings.TrimSpace(fragment)
+	}
+}
+
+// lineBreaks are the characters that would make one fragment span several output lines.
+const lineBreaks = "\r\n\u2028\u2029"
+
+// splitEmbeddedLineBreaks re-splits any fragment containing a line break, such as a lone \r or a
+// Unicode line separator, into clean trimmed fragments so that one fragment never spans several
+// output lines, whatever earlier stages produced.
+func (o Options) splitEmbeddedLineBreaks(sentences []Sentence) []Sentence {
+	split := make([]Sentence, 0, len(sentences))
+	for _, s := range sentences {
+		if !strings.ContainsAny(s.Text, lineBreaks) {
+			split = append(split, s)
+			continue
+		}
+		var parts []Sentence
+		for _, part := range strings.FieldsFunc(s.Text, func(r rune) bool { return strings.ContainsRune(lineBreaks, r) }) {
+			if strings.TrimSpace(part) != "" {
+				parts = append(parts, Sentence{Text: o.trim(part), SourceLine: s.SourceLine, Matcher: MatcherLineBreak, Original: s.Original})
+			}
+		}
+		if len(parts) > 0 {
+			parts[len(parts)-1].Matcher = s.Matcher // The last part ends where the whole fragment did
+		}
+		split = append(split, parts...)
+	}
+	return split
+}
+
+// collapseAdjacent drops every sentence whose text equals that of the sentence before it, returning
+// the kept and the dropped sentences.
+func collapseAdjacent(sentences []Sentence) (kept, dropped []Sentence) {
+	kept = sentences[:0]
+	for _, s := range sentences {
+		if len(kept) == 0 || s.Text != kept[len(kept)-1].Text {
+			kept = append(kept, s)
+		} else {
+			dropped = append(dropped, s)
+		}
+	}
+	return kept, dropped
+}
+
+// dedupSentences drops every sentence equal to an earlier one under mode, returning the kept and
+// the dropped sentences. The first occurrence keeps its original form. When cache is positive,
+// only the cache most recently seen sentences are remembered; see recentSet.
+func dedupSentences(sentences []Sentence, mode string, cache int) (kept, dropped []Sentence) {
+	seen := newRecentSet(cache)
+	kept = sentences[:0]
+	for _, s := range sentences {
+		key := s.Text
+		if mode == DedupNormalized {
+			key = dedupKey(key)
+		}
+		if !seen.add(key) {
+			kept = append(kept, s)
+		} else {
+			dropped = append(dropped, s)
+		}
+	}
+	return kept, dropped
+}
+
+// dedupWindowSentences drops every sentence equal under mode to one occurring on the same source
+// line or at most window lines before it, returning the kept and the dropped sentences. Every
+// occurrence, dropped or not, restarts the window, so a refrain repeated every few lines is kept
+// once. Only the sentences of the last window lines are remembered, in a queue ordered by line.
+func dedupWindowSentences(sentences []Sentence, mode string, window int) (kept, dropped []Sentence) {
+	type occurrence struct {
+		key  string
+		line int
+	}
+	var recent []occurrence
+	lastLine := map[string]int{}
+	kept = sentences[:0]
+	for _, s := range sentences {
+		for len(recent) > 0 && s.SourceLine-recent[0].line > window {
+			if lastLine[recent[0].key] == recent[0].line {
+				delete(lastLine, recent[0].key)
+			}
+			recent = recent[1:]
+		}
+		key := s.Text
+		if mode == DedupNormalized {
+			key = dedupKey(key)
+		}
+		if _, seen := lastLine[key]; seen {
+			dropped = append(dropped, s)
+		} else {
+			kept = append(kept, s)
+		}
+		lastLine[key] = s.SourceLine
+		recent = append(recent, occurrence{key, s.SourceLine})
+	}
+	return kept, dropped
+}
+
+// recentSet is a set of strings that, given a positive capacity, forgets its least recently seen
+// member once it holds more than capacity of them.
+type recentSet struct {
+	capacity int
+	members  map[string]*list.Element
+	order    *list.List // Members, most recently seen first
+}
+
+// newRecentSet returns an empty recentSet; a capacity that is not positive means unbounded.
+func newRecentSet(capacity int) *recentSet {
+	return &recentSet{capacity: capacity, members: map[string]*list.Element{}, order: list.New()}
+}
+
+// add records key as seen most recently and reports whether it was already a member.
+func (s *recentSet) add(key string) bool {
+	if e, ok := s.members[key]; ok {
+		s.order.MoveToFront(e)
+		return true
+	}
+	s.members[key] = s.order.PushFront(key)
+	if s.capacity > 0 && s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.members, oldest.Value.(string))
+	}
+	return false
+}
+
+// dedupKey is the form DedupNormalized compares sentences by: lower-cased, with runs of whitespace
+// collapsed to one space and trailing punctuation removed, so "Hello  World." and "hello world" match.
+func dedupKey(text string) string {
+	key := strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	return strings.TrimRightFunc(key, func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSpace(r) })
+}
+
+// excludeSentences drops the sentences whose text is in exclude, returning the kept and the
+// dropped sentences.
+func excludeSentences(sentences []Sentence, exclude map[string]bool) (kept, dropped []Sentence) {
+	kept = sentences[:0]
+	for _, s := range sentences {
+		if !exclude[s.Text] {
+			kept = append(kept, s)
+		} else {
+			dropped = append(dropped, s)
+		}
+	}
+	return kept, dropped
+}
+
+// punctuationSentences drops the sentences whose punctuationRatio exceeds limit, returning the kept
+// and the dropped sentences.
+func punctuationSentences(sentences []Sentence, limit float64) (kept, dropped []Sentence) {
+	kept = sentences[:0]
+	for _, s := range sentences {
+		if punctuationRatio(s.Text) <= limit {
+			kept = append(kept, s)
+		} else {
+			dropped = append(dropped, s)
+		}
+	}
+	return kept, dropped
+}
+
+// punctuationRatio returns the fraction of the runes of text that are punctuation (\p{P}).
+func punctuationRatio(text string) float64 {
+	runes, punct := 0, 0
+	for _, r := range text {
+		runes++
+		if unicode.IsPunct(r) {
+			punct++
+		}
+	}
+	if runes == 0 {
+		return 0
+	}
+	return float64(punct) / float64(runes)
+}
+
+// bracketPairs maps each closing bracket or quote checked by Options.RequireBalanced to its
+// opening one.
+var bracketPairs = map[rune]rune{')': '(', '）': '（', '】': '【', '」': '「', '”': '“', '’': '‘'}
+
+// balancedSentences keeps the sentences that isBalanced accepts, returning the kept and the
+// dropped sentences.
+func balancedSentences(sentences []Sentence) (kept, dropped []Sentence) {
+	kept = sentences[:0]
+	for _, s := range sentences {
+		if isBalanced(s.Text) {
+			kept = append(kept, s)
+		} else {
+			dropped = append(dropped, s)
+		}
+	}
+	return kept, dropped
+}
+
+// isBalanced reports whether every bracket and quote of bracketPairs in text is closed by its
+// partner, in nesting order, so （“好”） passes but （“好）” and “好 do not. A ’ between two letters
+// is an apostrophe, as in don’t, rather than a closing quote.
+func isBalanced(text string) bool {
+	openers := map[rune]bool{}
+	for _, opening := range bracketPairs {
+		openers[opening] = true
+	}
+	var stack []rune
+	runes := []rune(text)
+	for i, r := range runes {
+		if r == '’' && i > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i-1]) && unicode.IsLetter(runes[i+1]) {
+			continue
+		}
+		if openers[r] {
+			stack = append(stack, r)
+			continue
+		}
+		if opening, ok := bracketPairs[r]; ok {
+			if len(stack) == 0 || stack[len(stack)-1] != opening {
+				return false
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return len(stack) == 0
+}
+
+// targetSentences keeps the sentences whose PredominantScript is target, returning the kept and the
+// dropped sentences.
+func targetSentences(sentences []Sentence, target Script) (kept, dropped []Sentence) {
+	kept = sentences[:0]
+	for _, s := range sentences {
+		if PredominantScript(s.Text) == target {
+			kept = append(kept, s)
+		} else {
+			dropped = append(dropped, s)
+		}
+	}
+	return kept, dropped
+}
+
+// grepSentences keeps the sentences matching re, or with invert those not matching it, returning
+// the kept and the dropped sentences.
+func grepSentences(sentences []Sentence, re *regexp.Regexp, invert bool) (kept, dropped []Sentence) {
+	kept = sentences[:0]
+	for _, s := range sentences {
+		if re.MatchString(s.Text) != invert {
+			kept = append(kept, s)
+		} else {
+			dropped = append(dropped, s)
+		}
+	}
+	return kept, dropped
+}
+
+// emptyLines returns the non-blank lines that no sentence comes from. Blocklisted lines are already
+// blank, so they are not reported.
+func emptyLines(lines []string, sentences []Sentence) []EmptyLine {
+	produced := make(map[int]bool, len(sentences))
+	for _, s := range sentences {
+		produced[s.SourceLine] = true
+	}
+	var empty []EmptyLine
+	for i, line := range lines {
+		if produced[i+1] || strings.TrimSpace(line) == "" {
+			continue
+		}
+		snippet := strings.TrimSpace(line)
+		if runes := []rune(snippet); len(runes) > emptyLineSnippetRunes {
+			snippet = string(runes[:emptyLineSnippetRunes]) + "…"
+		}
+		empty = append(empty, EmptyLine{Line: i + 1, Snippet: snippet})
+	}
+	return empty
+}
+
+// blockLines empties, in place, the lines matching any of the patterns, so they yield no fragment.
+func blockLines(lines []string, patterns []*regexp.Regexp) {
+	if len(patterns) == 0 {
+		return
+	}
+	for i, line := range lines {
+		for _, re := range patterns {
+			if re.MatchString(line) {
+				lines[i] = ""
+				break
+			}
+		}
+	}
+}
+
+// limitLines empties, in place, the lines before the 1-based line from, so they yield no fragment
+// but keep the line numbers of the rest. The lines after Options.ToLine are never scanned.
+func limitLines(lines []string, from int) {
+	for i := 0; i+1 < from && i < len(lines); i++ {
+		lines[i] = ""
+	}
+}
+
+// reverseSentences reverses sentences in place.
+func reverseSentences(sentences []Sentence) {
+	for i, j := 0, len(sentences)-1; i < j; i, j = i+1, j-1 {
+		sentences[i], sentences[j] = sentences[j], sentences[i]
+	}
+}
+
+// scanTextLines splits text into its lines with ScanLines, stopping after limit lines when it is
+// positive. The whole text is in memory, so a line may be as long as the text itself.
+func scanTextLines(text string, limit int) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(text)+1)
+	scanner.Split(ScanLines)
+	for (limit <= 0 || len(lines) < limit) && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// ScanLines is a bufio.SplitFunc like bufio.ScanLines that also ends a line at a lone \r, so
+// files mixing \r\n, \n and classic Mac \r line endings are split into their real lines.
+func ScanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// A \r at the end of the buffer may be the first half of \r\n
+		if i+1 == len(data) && !atEOF {
+			return 0, nil, nil
+		}
+		if i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// mergeColonClauses joins every fragment ending in a colon with the fragments after it up to and
+// including the next one ending in a terminal mark (statement, exclamation or question), so
+// 他说：你好，世界。 stays one fragment instead of orphaning the speaker.
+func mergeColonClauses(fragments []string) []string {
+	var merged []string
+	open := false
+	for _, fragment := range fragments {
+		if open {
+			merged[len(merged)-1] += fragment
+		} else {
+			merged = append(merged, fragment)
+		}
+		last := strings.TrimRightFunc(merged[len(merged)-1], unicode.IsSpace)
+		if r, _ := utf8.DecodeLastRuneInString(last); strings.ContainsRune(colons, r) {
+			open = true
+		} else if open {
+			switch EndType(last) {
+			case EndStatement, EndExclamation, EndQuestion:
+				open = false
+			}
+		}
+	}
+	return merged
+}
+
+// splitAfterPunctuation cuts line after every match of punctuationRegex. When maxFragments is
+// positive, at most that many pieces are produced and the remainder of the line is kept whole as
+// the last piece; capped reports whether that remainder still contained further marks.
+func splitAfterPunctuation(line string, punctuationRegex *regexp.Regexp, maxFragments int) (fragments []string, capped bool) {
+	limit := -1
+	if maxFragments > 0 {
+		limit = maxFragments - 1
+	}
+	start := 0
+	for _, loc := range punctuationRegex.FindAllStringIndex(line, limit) {
+		fragments = append(fragments, line[start:loc[1]])
+		start = loc[1]
+	}
+	rest := line[start:]
+	fragments = append(fragments, rest)
+	if limit >= 0 && len(fragments) == maxFragments {
+		loc := punctuationRegex.FindStringIndex(rest)
+		capped = loc != nil && loc[1] < len(rest)
+	}
+	return fragments, capped
+}
+
+// Join combines the sentence texts into the one-per-line text output.
+func Join(sentences []Sentence) string {
+	lines := make([]string, len(sentences))
+	for i, s := range sentences {
+		lines[i] = s.Text
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,397 @@
+package sentencer
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// withOptions returns DefaultOptions changed by configure.
+func withOptions(configure func(o *Options)) Options {
+	opts := DefaultOptions()
+	if configure != nil {
+		configure(&opts)
+	}
+	return opts
+}
+
+func TestProcessTextMixedParagraph(t *testing.T) {
+	result := ProcessText("你好，世界。Hello there\n\n  我爱Go。  ", DefaultOptions())
+
+	wantCombined := []string{"你好，", "世界。", "Hello there", "我爱Go。"}
+	if !reflect.DeepEqual(result.Combined, wantCombined) {
+		t.Errorf("Combined = %q, want %q", result.Combined, wantCombined)
+	}
+	if want := []string{"你好，", "世界。", "我爱Go。"}; !reflect.DeepEqual(result.Chinese, want) {
+		t.Errorf("Chinese = %q, want %q", result.Chinese, want)
+	}
+	if want := []string{"Hello there"}; !reflect.DeepEqual(result.English, want) {
+		t.Errorf("English = %q, want %q", result.English, want)
+	}
+	wantLines := []int{1, 1, 1, 3}
+	for i, s := range result.Sentences {
+		if s.SourceLine != wantLines[i] {
+			t.Errorf("sentence %q: SourceLine = %d, want %d", s.Text, s.SourceLine, wantLines[i])
+		}
+	}
+	stats := result.Stats
+	if stats.Lines != 3 || stats.Sentences != 4 || stats.ChineseSentences != 3 || stats.EnglishSentences != 1 {
+		t.Errorf("Stats = %+v, want 3 lines, 4 sentences, 3 Chinese and 1 English", stats)
+	}
+}
+
+func TestProcessTextSplitting(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		configure func(o *Options)
+		want      []string
+	}{
+		{"default marks", "春眠不觉晓，处处闻啼鸟。夜来风雨声？", nil, []string{"春眠不觉晓，", "处处闻啼鸟。", "夜来风雨声？"}},
+		{"enumeration comma", "苹果、香蕉、橙子", nil, []string{"苹果、", "香蕉、", "橙子"}},
+		{"no enumeration comma", "苹果、香蕉、橙子", func(o *Options) { o.SplitEnum = false }, []string{"苹果、香蕉、橙子"}},
+		{"vertical full stop", "竖排文字︒转换而来", nil, []string{"竖排文字︒", "转换而来"}},
+		{"custom terminators", "你好。Hi! Bye? 再见，朋友", func(o *Options) { o.Terminators = "。!?" },
+			[]string{"你好。", "Hi!", "Bye?", "再见，朋友"}},
+		{"ideographic space line", "你好。\n　　\n世界。", nil, []string{"你好。", "世界。"}},
+		{"whitespace-only fragment", "你好，　 ，世界", nil, []string{"你好，", "，", "世界"}},
+		{"mixed line endings", "一行\r\n二行\r三行\n四行", nil, []string{"一行", "二行", "三行", "四行"}},
+		{"embedded line separator", "上半句 下半句。", nil, []string{"上半句", "下半句。"}},
+		{"script split", "你好hello世界", func(o *Options) { o.ScriptSplit = true }, []string{"你好", "hello", "世界"}},
+		{"script split keeps digits", "共3个apple", func(o *Options) { o.ScriptSplit = true }, []string{"共3个", "apple"}},
+		{"english tokenizer", `Dr. Smith went home. He slept. "Why?" she asked. It cost 3.14 dollars.`,
+			func(o *Options) { o.EnglishTokenizer = true },
+			[]string{"Dr. Smith went home.", "He slept.", `"Why?" she asked.`, "It cost 3.14 dollars."}},
+		{"english tokenizer initials", "J. R. R. Tolkien wrote it. E.g. this one.", func(o *Options) { o.EnglishTokenizer = true },
+			[]string{"J. R. R. Tolkien wrote it.", "E.g. this one."}},
+		{"english tokenizer off", "Dr. Smith went home. He slept.", nil, []string{"Dr. Smith went home. He slept."}},
+		{"keep units decimal", "长3.5米", func(o *Options) { o.Terminators = "。."; o.KeepUnits = true }, []string{"长3.5米"}},
+		{"keep units year", "2021年。下一句", func(o *Options) { o.KeepUnits = true }, []string{"2021年。", "下一句"}},
+		{"keep units thousands", "共1，000个", func(o *Options) { o.KeepUnits = true }, []string{"共1，000个"}},
+		{"keep units after terminator", "我们在2019。年底又见面了。", func(o *Options) { o.KeepUnits = true }, []string{"我们在2019。", "年底又见面了。"}},
+		{"without keep units", "共1，000个", nil, []string{"共1，", "000个"}},
+		{"collapse question marks", "真的吗？？？好", func(o *Options) { o.CollapsePunct = true }, []string{"真的吗？", "好"}},
+		{"collapse exclamation marks", "wow!!! ok", func(o *Options) { o.CollapsePunct = true; o.Terminators = "!" }, []string{"wow!", "ok"}},
+		{"without collapse", "真的吗？？？", nil, []string{"真的吗？", "？", "？"}},
+		{"whole file", "这是一个被\n换行打断的句子。\nwrapped English\nline", func(o *Options) { o.WholeFile = true },
+			[]string{"这是一个被换行打断的句子。", "wrapped English line"}},
+		{"line by line", "这是一个被\n换行打断的句子。", nil, []string{"这是一个被", "换行打断的句子。"}},
+		{"dash divider", "第一章\n—————\n正文", func(o *Options) { o.KeepDividers = true }, []string{"第一章", "---", "正文"}},
+		{"star divider", "第一章\n＊＊＊\n正文", func(o *Options) { o.KeepDividers = true }, []string{"第一章", "---", "正文"}},
+		{"short run is no divider", "第一章\n＊＊\n正文", func(o *Options) { o.KeepDividers = true }, []string{"第一章", "＊＊", "正文"}},
+		{"table glyphs kept", "姓名|年龄|城市", nil, []string{"姓名|年龄|城市"}},
+		{"table glyphs split", "姓名|年龄丨城市", func(o *Options) { o.SplitTableGlyphs = true }, []string{"姓名", "年龄", "城市"}},
+		{"hard colon", "他说：你好。", nil, []string{"他说：", "你好。"}},
+		{"soft colon", "他说：你好，世界。再见", func(o *Options) { o.Colon = ColonSoft }, []string{"他说：你好，世界。", "再见"}},
+		{"no colon", "他说：你好，世界。", func(o *Options) { o.Colon = ColonNone }, []string{"他说：你好，", "世界。"}},
+		{"zh semicolon", "甲；乙", nil, []string{"甲；", "乙"}},
+		{"no zh semicolon", "甲；乙", func(o *Options) { o.NoZhSemicolon = true }, []string{"甲；乙"}},
+		{"en semicolon off", "a; b", nil, []string{"a; b"}},
+		{"en semicolon", "a; b", func(o *Options) { o.EnSemicolon = true }, []string{"a;", "b"}},
+		{"en semicolon keeps zh", "甲；乙", func(o *Options) { o.EnSemicolon = true; o.NoZhSemicolon = true }, []string{"甲；乙"}},
+		{"en semicolon needs space", "&amp;x", func(o *Options) { o.EnSemicolon = true }, []string{"&amp;x"}},
+		{"trim both", "    indented，  ", nil, []string{"indented，"}},
+		{"trim right", "    indented，  ", func(o *Options) { o.Trim = TrimRight }, []string{"    indented，"}},
+		{"trim none", "    indented，  ", func(o *Options) { o.Trim = TrimNone }, []string{"    indented，"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ProcessText(tt.input, withOptions(tt.configure)).Combined
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Combined = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessTextFilters(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		configure    func(o *Options)
+		want         []string
+		wantRejected []string // Filter of every rejected sentence, in order
+	}{
+		{"grep", "苹果公司发布新品。香蕉很甜。", func(o *Options) { o.Grep = regexp.MustCompile("公司") },
+			[]string{"苹果公司发布新品。"}, []string{RejectedGrep}},
+		{"grep invert", "苹果公司发布新品。香蕉很甜。", func(o *Options) { o.Grep = regexp.MustCompile("公司"); o.GrepInvert = true },
+			[]string{"香蕉很甜。"}, []string{RejectedGrep}},
+		{"collapse adjacent", "A\nA\nA\nB\nA", func(o *Options) { o.CollapseAdjacent = true },
+			[]string{"A", "B", "A"}, []string{RejectedAdjacent, RejectedAdjacent}},
+		{"dedup exact", "Hello.\nhello\nHello.", func(o *Options) { o.Dedup = DedupExact },
+			[]string{"Hello.", "hello"}, []string{RejectedDuplicate}},
+		{"dedup normalized", "Hello.\nhello\nHello  World!\nhello world", func(o *Options) { o.Dedup = DedupNormalized },
+			[]string{"Hello.", "Hello  World!"}, []string{RejectedDuplicate, RejectedDuplicate}},
+		{"dedup cache", "A\nB\nC\nA\nC", func(o *Options) { o.Dedup = DedupExact; o.DedupCache = 2 },
+			[]string{"A", "B", "C", "A"}, []string{RejectedDuplicate}},
+		{"dedup window", "副歌\n一\n副歌\n二\n三\n四\n副歌", func(o *Options) { o.Dedup = DedupExact; o.DedupWindow = 2 },
+			[]string{"副歌", "一", "二", "三", "四", "副歌"}, []string{RejectedDuplicate}},
+		{"exclude", "旧句子。新句子。", func(o *Options) { o.Exclude = map[string]bool{"旧句子。": true} },
+			[]string{"新句子。"}, []string{RejectedExisting}},
+		{"punct ratio at limit", "好，", func(o *Options) { o.MaxPunctRatio = 0.5 }, []string{"好，"}, nil},
+		{"punct ratio above limit", "……，，？！\n好", func(o *Options) { o.MaxPunctRatio = 0.5; o.SplitEnum = false; o.Terminators = "\n" },
+			[]string{"好"}, []string{RejectedPunctuation}},
+		{"balanced", "（“好”）\n（“好）”\n“好\ndon’t", func(o *Options) { o.RequireBalanced = true },
+			[]string{"（“好”）", "don’t"}, []string{RejectedUnbalanced, RejectedUnbalanced}},
+		{"lang hint zh", "你好。Hello there. 123", func(o *Options) { o.LangHint = LangHintChinese; o.EnglishTokenizer = true },
+			[]string{"你好。"}, []string{RejectedLangHint}},
+		{"lang hint en", "你好。Hello there", func(o *Options) { o.LangHint = LangHintEnglish },
+			[]string{"Hello there"}, []string{RejectedLangHint}},
+		{"target zh", "我用iPhone拍照\nDownload the 文件\n42", func(o *Options) { o.Target = TargetChinese },
+			[]string{"我用iPhone拍照"}, []string{RejectedTarget, RejectedTarget}},
+		{"target en", "我用iPhone拍照\nDownload the new 文件", func(o *Options) { o.Target = TargetEnglish },
+			[]string{"Download the new 文件"}, []string{RejectedTarget}},
+		{"blocklist", "正文。\n版权所有 © 2021\n第 3 页\n更多。", func(o *Options) {
+			o.Blocklist = []*regexp.Regexp{regexp.MustCompile("^版权所有"), regexp.MustCompile(`^第 \d+ 页$`)}
+		}, []string{"正文。", "更多。"}, nil},
+		{"line range", "一\n二\n三\n四", func(o *Options) { o.FromLine = 2; o.ToLine = 3 }, []string{"二", "三"}, nil},
+		{"open line range", "一\n二\n三", func(o *Options) { o.FromLine = 2 }, []string{"二", "三"}, nil},
+		{"reverse", "一，二，三", func(o *Options) { o.Reverse = true }, []string{"三", "二，", "一，"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := withOptions(tt.configure)
+			opts.KeepRejected = true
+			result := ProcessText(tt.input, opts)
+			if !reflect.DeepEqual(result.Combined, tt.want) {
+				t.Errorf("Combined = %q, want %q", result.Combined, tt.want)
+			}
+			var rejected []string
+			for _, r := range result.Rejected {
+				rejected = append(rejected, r.Filter)
+			}
+			if !reflect.DeepEqual(rejected, tt.wantRejected) {
+				t.Errorf("rejected filters = %q, want %q", rejected, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestProcessTextLineRangeKeepsLineNumbers(t *testing.T) {
+	result := ProcessText("一\n二\n三", withOptions(func(o *Options) { o.FromLine = 3 }))
+	if len(result.Sentences) != 1 || result.Sentences[0].SourceLine != 3 {
+		t.Errorf("Sentences = %+v, want only 三 from line 3", result.Sentences)
+	}
+	if result.Stats.Lines != 3 {
+		t.Errorf("Stats.Lines = %d, want 3", result.Stats.Lines)
+	}
+}
+
+func TestProcessTextToLineStopsScanning(t *testing.T) {
+	result := ProcessText("一\n二\n三\n四", withOptions(func(o *Options) { o.FromLine = 2; o.ToLine = 3 }))
+	if got := result.Combined; !reflect.DeepEqual(got, []string{"二", "三"}) {
+		t.Errorf("Combined = %q, want [二 三]", got)
+	}
+	if result.Stats.Lines != 3 {
+		t.Errorf("Stats.Lines = %d, want 3, the lines after -to-line not scanned", result.Stats.Lines)
+	}
+}
+
+func TestMaxFragments(t *testing.T) {
+	bomb := strings.Repeat("，", 100000)
+	result := ProcessText("正常。\n"+bomb, DefaultOptions())
+	if got := len(result.Combined); got != DefaultMaxFragments+1 {
+		t.Errorf("comma bomb gave %d sentences, want %d", got, DefaultMaxFragments+1)
+	}
+	if want := []int{2}; !reflect.DeepEqual(result.Stats.CappedLines, want) {
+		t.Errorf("CappedLines = %v, want %v", result.Stats.CappedLines, want)
+	}
+
+	tests := []struct {
+		max        int
+		want       []string
+		wantCapped bool
+	}{
+		{0, []string{"a，", "b，", "c，", "d，", "e"}, false},
+		{3, []string{"a，", "b，", "c，d，e"}, true},
+		{5, []string{"a，", "b，", "c，", "d，", "e"}, false},
+		{4, []string{"a，", "b，", "c，", "d，e"}, true},
+	}
+	for _, tt := range tests {
+		result := ProcessText("a，b，c，d，e", withOptions(func(o *Options) { o.MaxFragments = tt.max }))
+		if !reflect.DeepEqual(result.Combined, tt.want) {
+			t.Errorf("MaxFragments %d: Combined = %q, want %q", tt.max, result.Combined, tt.want)
+		}
+		if capped := len(result.Stats.CappedLines) > 0; capped != tt.wantCapped {
+			t.Errorf("MaxFragments %d: capped = %v, want %v", tt.max, capped, tt.wantCapped)
+		}
+	}
+}
+
+func TestStreams(t *testing.T) {
+	tests := []struct {
+		name                        string
+		input                       string
+		configure                   func(o *Options)
+		chinese, english, bilingual []string
+		numeric                     []string
+		wantChineseN, wantEnglishN  int
+	}{
+		{"bilingual", "纯中文。\nPure English\n我爱Go语言。", nil,
+			[]string{"纯中文。", "我爱Go语言。"}, []string{"Pure English"}, []string{"我爱Go语言。"}, nil, 2, 1},
+		{"numeric", "3.5kg\n10%\n今天天气很好。", nil,
+			[]string{"今天天气很好。"}, []string{"3.5kg"}, nil, []string{"3.5kg", "10%"}, 1, 1},
+		{"digits neutral", "12345", nil, nil, nil, nil, []string{"12345"}, 0, 0},
+		{"digits chinese", "12345", func(o *Options) { o.Digits = DigitsChinese }, []string{"12345"}, nil, nil, []string{"12345"}, 1, 0},
+		{"digits english", "12345", func(o *Options) { o.Digits = DigitsEnglish }, nil, []string{"12345"}, nil, []string{"12345"}, 0, 1},
+		{"overlap both", "你好world", nil, []string{"你好world"}, nil, []string{"你好world"}, nil, 1, 0},
+		{"overlap split", "你好world", func(o *Options) { o.Overlap = OverlapSplit }, []string{"你好"}, []string{"world"}, nil, nil, 1, 1},
+		{"overlap drop", "你好world", func(o *Options) { o.Overlap = OverlapDrop }, []string{"你好"}, []string{"world"}, []string{"你好world"}, nil, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ProcessText(tt.input, withOptions(tt.configure))
+			for _, stream := range []struct {
+				name      string
+				got, want []string
+			}{
+				{"Chinese", result.Chinese, tt.chinese},
+				{"English", result.English, tt.english},
+				{"Bilingual", result.Bilingual, tt.bilingual},
+				{"Numeric", result.Numeric, tt.numeric},
+			} {
+				if !reflect.DeepEqual(stream.got, stream.want) {
+					t.Errorf("%s = %q, want %q", stream.name, stream.got, stream.want)
+				}
+			}
+			if result.Stats.ChineseSentences != tt.wantChineseN || result.Stats.EnglishSentences != tt.wantEnglishN {
+				t.Errorf("Stats = %d Chinese and %d English, want %d and %d",
+					result.Stats.ChineseSentences, result.Stats.EnglishSentences, tt.wantChineseN, tt.wantEnglishN)
+			}
+		})
+	}
+}
+
+func TestOverlapDropKeepsCombinedWhole(t *testing.T) {
+	result := ProcessText("你好world", withOptions(func(o *Options) { o.Overlap = OverlapDrop }))
+	if want := []string{"你好world"}; !reflect.DeepEqual(result.Combined, want) {
+		t.Errorf("Combined = %q, want %q", result.Combined, want)
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		configure func(o *Options)
+		want      []string
+	}{
+		{"punctuation and end", "你好，世界", nil, []string{MatcherPunctuation, MatcherEnd}},
+		{"english end", "Hello there", nil, []string{MatcherEnd}},
+		{"script split", "你好hello", func(o *Options) { o.ScriptSplit = true }, []string{MatcherScriptSplit, MatcherEnd}},
+		{"english tokenizer", "One here. Two here.", func(o *Options) { o.EnglishTokenizer = true }, []string{MatcherEnglishTokenizer, MatcherEnd}},
+		{"table glyph", "甲|乙", func(o *Options) { o.SplitTableGlyphs = true }, []string{MatcherTableGlyph, MatcherEnd}},
+		{"line break", "甲 乙。", nil, []string{MatcherLineBreak, MatcherPunctuation}},
+		{"divider", "＊＊＊", func(o *Options) { o.KeepDividers = true }, []string{MatcherDivider}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, s := range ProcessText(tt.input, withOptions(tt.configure)).Sentences {
+				got = append(got, s.Matcher)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepOriginal(t *testing.T) {
+	opts := withOptions(func(o *Options) { o.KeepOriginal = true; o.CanonPunct = true })
+	result := ProcessText("  你好,世界。 ", opts)
+	if len(result.Sentences) != 1 {
+		t.Fatalf("Sentences = %+v, want one", result.Sentences)
+	}
+	if s := result.Sentences[0]; s.Text != "你好，世界。" || s.Original != "  你好,世界。" {
+		t.Errorf("sentence = %q from %q, want 你好，世界。 from the untrimmed fragment", s.Text, s.Original)
+	}
+}
+
+func TestReportEmptyLines(t *testing.T) {
+	opts := withOptions(func(o *Options) { o.ReportEmptyLines = true; o.SplitTableGlyphs = true })
+	result := ProcessText("正文\n ||| \n\n  \n下一行", opts)
+	want := []EmptyLine{{Line: 2, Snippet: "|||"}}
+	if !reflect.DeepEqual(result.Stats.EmptyLines, want) {
+		t.Errorf("EmptyLines = %+v, want %+v", result.Stats.EmptyLines, want)
+	}
+}
+
+func TestTimings(t *testing.T) {
+	result := ProcessText(strings.Repeat("你好，世界。\n", 1000), DefaultOptions())
+	timings := result.Stats.Timings
+	if timings.Split <= 0 || timings.Clean < 0 || timings.Read != 0 {
+		t.Errorf("Timings = %+v, want a positive Split, a non-negative Clean and no Read", timings)
+	}
+}
+
+func TestProcessTextContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := ProcessTextContext(ctx, strings.Repeat("你好。\n", 1000), DefaultOptions())
+	if err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if result.Stats.Lines != 0 || len(result.Sentences) != 0 {
+		t.Errorf("got %d lines and %d sentences after cancellation, want none", result.Stats.Lines, len(result.Sentences))
+	}
+}
+
+func TestScanLines(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"a\r\nb\nc\rd", []string{"a", "b", "c", "d"}},
+		{"a\r\n", []string{"a"}},
+		{"a\r", []string{"a"}},
+		{"\r\r\n\n", []string{"", "", ""}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := scanTextLines(tt.input, 0); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("scanTextLines(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTerminators(t *testing.T) {
+	tests := []struct {
+		marks   string
+		wantErr bool
+	}{
+		{"。！？.!?", false},
+		{"]^-\\", false},
+		{"", true},
+		{"。 ", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateTerminators(tt.marks); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateTerminators(%q) = %v, want error %v", tt.marks, err, tt.wantErr)
+		}
+	}
+	// Every special character is escaped, so the set matches only itself
+	result := ProcessText("a]b^c-d\\e", withOptions(func(o *Options) { o.Terminators = "]^-\\" }))
+	if want := []string{"a]", "b^", "c-", "d\\", "e"}; !reflect.DeepEqual(result.Combined, want) {
+		t.Errorf("Combined = %q, want %q", result.Combined, want)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	if got := Join([]Sentence{{Text: "一"}, {Text: "二"}}); got != "一\n二" {
+		t.Errorf("Join = %q, want %q", got, "一\n二")
+	}
+}
+
+func BenchmarkProcessText(b *testing.B) {
+	paragraph := "今天天气很好，我们去公园散步吧！你觉得怎么样？Hello world. It is a nice day; let's go.2021年3月15日，共计1,000人参加了会议：北京、上海、广州。\n"
+	text := strings.Repeat(paragraph, 1000)
+	opts := DefaultOptions()
+	b.SetBytes(int64(len(text)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ProcessText(text, opts)
+	}
+}
@@ -0,0 +1,66 @@
+package sentencer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// measureUnits lists common Chinese measure words and units that belong to a preceding number.
+const measureUnits = "个只件条张本头匹位名次岁年月日号时分秒天周米克斤两吨元块角毛升度倍层页%％"
+
+// isNumeric reports whether text is dominated by a number, such as 3.5kg, 2021年 or 10%: it holds
+// digits and no more letters (Latin or Han) than digits. Spaces, punctuation and symbols do not count.
+func isNumeric(text string) bool {
+	digits, letters := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case unicode.IsLetter(r):
+			letters++
+		}
+	}
+	return digits > 0 && digits >= letters
+}
+
+// attachUnits merges a fragment that ends in a digit followed by one of numberMarks with the next
+// fragment from the same line when that one continues the number into a unit, so splitting
+// on e.g. "." or "，" cannot separate "3." from "5米" or "1，" from "000个".
+func attachUnits(sentences []Sentence) []Sentence {
+	merged := make([]Sentence, 0, len(sentences))
+	for _, s := range sentences {
+		if n := len(merged); n > 0 {
+			prev := &merged[n-1]
+			if prev.SourceLine == s.SourceLine && endsWithDigitMark(prev.Text) && startsWithUnit(s.Text) {
+				prev.Text += s.Text
+				prev.Matcher = s.Matcher
+				prev.Original += s.Original
+				continue
+			}
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// numberMarks are the split marks that can stand inside a number, as a decimal point or a
+// thousands separator. A terminator such as 。 after a digit ends the sentence, as in 我们在2019。
+const numberMarks = ".．,，、"
+
+// endsWithDigitMark reports whether text ends with a digit immediately followed by one of numberMarks.
+func endsWithDigitMark(text string) bool {
+	mark, size := utf8.DecodeLastRuneInString(text)
+	if size == 0 || !strings.ContainsRune(numberMarks, mark) {
+		return false
+	}
+	digit, _ := utf8.DecodeLastRuneInString(text[:len(text)-size])
+	return unicode.IsDigit(digit)
+}
+
+// startsWithUnit reports whether text starts with an optional run of digits followed by a measure unit.
+func startsWithUnit(text string) bool {
+	rest := strings.TrimLeftFunc(text, unicode.IsDigit)
+	r, _ := utf8.DecodeRuneInString(rest)
+	return strings.ContainsRune(measureUnits, r)
+}
@@ -0,0 +1,51 @@
+package sentencer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsNumeric(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"3.5kg", true},
+		{"10%", true},
+		{"2021年", true},
+		{"１２３", true},
+		{"今天天气很好。", false},
+		{"COVID19", false},
+		{"……", false},
+	}
+	for _, tt := range tests {
+		if got := isNumeric(tt.text); got != tt.want {
+			t.Errorf("isNumeric(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestAttachUnits(t *testing.T) {
+	tests := []struct {
+		name      string
+		sentences []Sentence
+		want      []string
+	}{
+		{"decimal", []Sentence{{Text: "3.", SourceLine: 1}, {Text: "5米", SourceLine: 1}}, []string{"3.5米"}},
+		{"thousands", []Sentence{{Text: "1，", SourceLine: 1}, {Text: "000个", SourceLine: 1}}, []string{"1，000个"}},
+		{"other line", []Sentence{{Text: "3.", SourceLine: 1}, {Text: "5米", SourceLine: 2}}, []string{"3.", "5米"}},
+		{"no unit", []Sentence{{Text: "第3，", SourceLine: 1}, {Text: "然后", SourceLine: 1}}, []string{"第3，", "然后"}},
+		{"no digit", []Sentence{{Text: "好，", SourceLine: 1}, {Text: "个人", SourceLine: 1}}, []string{"好，", "个人"}},
+		{"terminator", []Sentence{{Text: "我们在2019。", SourceLine: 1}, {Text: "年底又见面了。", SourceLine: 1}}, []string{"我们在2019。", "年底又见面了。"}},
+		{"exclamation", []Sentence{{Text: "3！", SourceLine: 1}, {Text: "个人", SourceLine: 1}}, []string{"3！", "个人"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, s := range attachUnits(tt.sentences) {
+			got = append(got, s.Text)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: attachUnits = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
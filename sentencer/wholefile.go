@@ -0,0 +1,49 @@
+package sentencer
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// joinLines joins the preprocessed, trimmed lines into one stream for Options.WholeFile, so text
+// wrapped at arbitrary points (e.g. extracted from PDFs) is split on punctuation only. Lines are
+// joined directly when either side of the break is CJK and with a space otherwise, so wrapped
+// English words stay apart. Blank lines are dropped. lineAt maps a byte offset in the joined stream
+// back to the input line it came from.
+func (o Options) joinLines(lines []string) (joined string, lineAt func(offset int) int) {
+	var b strings.Builder
+	var starts, numbers []int
+	for i, line := range lines {
+		line = strings.TrimSpace(o.preprocessLine(line))
+		if line == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(b.String())
+			next, _ := utf8.DecodeRuneInString(line)
+			if !isWide(prev) && !isWide(next) {
+				b.WriteByte(' ')
+			}
+		}
+		starts = append(starts, b.Len())
+		numbers = append(numbers, i+1)
+		b.WriteString(line)
+	}
+
+	lineAt = func(offset int) int {
+		i := sort.Search(len(starts), func(i int) bool { return starts[i] > offset }) - 1
+		if i < 0 {
+			return 1
+		}
+		return numbers[i]
+	}
+	return b.String(), lineAt
+}
+
+// isWide reports whether r belongs to the CJK ranges (ideographs, kana, hangul, full-width
+// forms and CJK punctuation) whose text is written without spaces between words.
+func isWide(r rune) bool {
+	return r >= 0x2E80 && r <= 0x9FFF || r >= 0xAC00 && r <= 0xD7AF || r >= 0xF900 && r <= 0xFAFF ||
+		r >= 0xFE30 && r <= 0xFE4F || r >= 0xFF00 && r <= 0xFFEF || r >= 0x20000 && r <= 0x3FFFF
+}
@@ -0,0 +1,37 @@
+package sentencer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinLines(t *testing.T) {
+	joined, lineAt := DefaultOptions().joinLines([]string{"这是一个被", "", "  换行打断的", "句子。Wrapped", "English text"})
+	if want := "这是一个被换行打断的句子。Wrapped English text"; joined != want {
+		t.Errorf("joined = %q, want %q", joined, want)
+	}
+	tests := []struct {
+		offset, want int
+	}{
+		{0, 1},
+		{len("这是一个被"), 3},
+		{len("这是一个被换行打断的"), 4},
+		{len(joined) - 1, 5},
+	}
+	for _, tt := range tests {
+		if got := lineAt(tt.offset); got != tt.want {
+			t.Errorf("lineAt(%d) = %d, want %d", tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestWholeFileSourceLines(t *testing.T) {
+	result := ProcessText("第一句在\n这里结束。第二句\n在这里。", withOptions(func(o *Options) { o.WholeFile = true }))
+	var lines []int
+	for _, s := range result.Sentences {
+		lines = append(lines, s.SourceLine)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("source lines = %v, want %v", lines, want)
+	}
+}
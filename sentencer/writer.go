@@ -0,0 +1,179 @@
+package sentencer
+
+import (
+	"io"
+	"strings"
+)
+
+// WriterSet holds the destination of each output stream. Nil writers are skipped.
+type WriterSet struct {
+	Chinese   io.Writer
+	English   io.Writer
+	Combined  io.Writer
+	Bilingual io.Writer
+	Numeric   io.Writer
+}
+
+// WriteStreams writes each stream of result to its writer in w as text, one sentence per line
+// without a trailing newline. The Combined stream is built from result.Sentences with
+// CombinedLines, so opts.CombinedSep applies to it.
+func WriteStreams(w WriterSet, result Result, opts Options) error {
+	streams := []struct {
+		w     io.Writer
+		lines []string
+	}{
+		{w.Chinese, result.Chinese},
+		{w.English, result.English},
+		{w.Combined, CombinedLines(result.Sentences, opts)},
+		{w.Bilingual, result.Bilingual},
+		{w.Numeric, result.Numeric},
+	}
+	for _, stream := range streams {
+		if stream.w == nil {
+			continue
+		}
+		if _, err := io.WriteString(stream.w, strings.Join(stream.lines, "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Layouts for Options.CombinedMode.
+const (
+	CombinedModeLines  = "lines"  // One fragment per line
+	CombinedModeBlocks = "blocks" // Runs of one language, each under a BlockLabel line
+)
+
+// CombinedLines returns the lines of the combined text stream, one per sentence. With
+// opts.CombinedSep set, adjacent Chinese and English fragments from the same input line
+// share one line joined by it. With opts.InlineTags, each fragment is prefixed with its
+// InlineTag. With opts.CombinedMode set to CombinedModeBlocks, every block is preceded by its
+// BlockLabel line, and CombinedSep is ignored.
+func CombinedLines(sentences []Sentence, opts Options) []string {
+	if opts.CombinedMode == CombinedModeBlocks {
+		var lines []string
+		for _, block := range opts.Blocks(sentences) {
+			lines = append(lines, BlockLabel(block.Script))
+			for _, s := range block.Sentences {
+				text := s.Text
+				if opts.InlineTags {
+					text = InlineTag(opts.Classify(text)) + text
+				}
+				lines = append(lines, text)
+			}
+		}
+		return lines
+	}
+	lines := make([]string, 0, len(sentences))
+	for i, s := range sentences {
+		text := s.Text
+		if opts.InlineTags {
+			text = InlineTag(opts.Classify(text)) + text
+		}
+		if i > 0 && opts.CombinedSep != "" && opts.pairsAcrossScripts(sentences[i-1], s) {
+			lines[len(lines)-1] += opts.CombinedSep + text
+			continue
+		}
+		lines = append(lines, text)
+	}
+	return lines
+}
+
+// InlineTag returns the language tag Options.InlineTags prefixes a fragment of script sc with:
+// "[zh]" for Chinese, "[en]" for English and an empty tag for fragments of neither script.
+func InlineTag(sc Script) string {
+	switch sc {
+	case ScriptChinese:
+		return "[zh]"
+	case ScriptEnglish:
+		return "[en]"
+	default:
+		return ""
+	}
+}
+
+// Block is a run of consecutive sentences of one language in the combined text stream.
+type Block struct {
+	Script    Script
+	Sentences []Sentence
+}
+
+// Blocks groups consecutive sentences of the same script into blocks, in order. Sentences of
+// neither script, such as bare numbers, join the block they appear in, or the following one at the
+// start; only input without any Chinese or English sentence yields a block of ScriptOther.
+func (o Options) Blocks(sentences []Sentence) []Block {
+	scripts := make([]Script, len(sentences))
+	for i, s := range sentences {
+		scripts[i] = o.Classify(s.Text)
+		if scripts[i] == ScriptOther && i > 0 {
+			scripts[i] = scripts[i-1]
+		}
+	}
+	first := 0
+	for first < len(scripts) && scripts[first] == ScriptOther {
+		first++
+	}
+	for i := 0; i < first && first < len(scripts); i++ {
+		scripts[i] = scripts[first] // Leading fragments of neither script join the first block
+	}
+	var blocks []Block
+	for i, s := range sentences {
+		if len(blocks) == 0 || blocks[len(blocks)-1].Script != scripts[i] {
+			blocks = append(blocks, Block{Script: scripts[i]})
+		}
+		last := &blocks[len(blocks)-1]
+		last.Sentences = append(last.Sentences, s)
+	}
+	return blocks
+}
+
+// BlockLabel returns the header line of a block of script sc in CombinedModeBlocks: "# [zh]",
+// "# [en]" or "# [other]".
+func BlockLabel(sc Script) string {
+	if tag := InlineTag(sc); tag != "" {
+		return "# " + tag
+	}
+	return "# [other]"
+}
+
+// AlignedRows pairs the Chinese and English fragments of each input line for a rough bilingual
+// alignment: the n-th Chinese fragment of a line is paired with its n-th English fragment, and
+// whichever column runs out first is left empty. Fragments of neither script are skipped.
+func AlignedRows(sentences []Sentence, opts Options) [][2]string {
+	var rows [][2]string
+	for start := 0; start < len(sentences); {
+		end := start
+		var zh, en []string
+		for ; end < len(sentences) && sentences[end].SourceLine == sentences[start].SourceLine; end++ {
+			switch opts.Classify(sentences[end].Text) {
+			case ScriptChinese:
+				zh = append(zh, sentences[end].Text)
+			case ScriptEnglish:
+				en = append(en, sentences[end].Text)
+			}
+		}
+		for i := 0; i < len(zh) || i < len(en); i++ {
+			var row [2]string
+			if i < len(zh) {
+				row[0] = zh[i]
+			}
+			if i < len(en) {
+				row[1] = en[i]
+			}
+			rows = append(rows, row)
+		}
+		start = end
+	}
+	return rows
+}
+
+// pairsAcrossScripts reports whether a and b come from the same input line and one is
+// Chinese while the other is English.
+func (o Options) pairsAcrossScripts(a, b Sentence) bool {
+	if a.SourceLine != b.SourceLine {
+		return false
+	}
+	sa, sb := o.Classify(a.Text), o.Classify(b.Text)
+	return sa != sb && sa != ScriptOther && sb != ScriptOther
+}
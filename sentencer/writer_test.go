@@ -0,0 +1,112 @@
+package sentencer
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWriteStreams(t *testing.T) {
+	result := ProcessText("你好，世界。\nHello there\n我用iPhone。\n3.5kg", DefaultOptions())
+	var zh, en, combined, bilingual, numeric bytes.Buffer
+	w := WriterSet{Chinese: &zh, English: &en, Combined: &combined, Bilingual: &bilingual, Numeric: &numeric}
+	if err := WriteStreams(w, result, DefaultOptions()); err != nil {
+		t.Fatalf("WriteStreams: %v", err)
+	}
+	tests := []struct {
+		name string
+		got  *bytes.Buffer
+		want string
+	}{
+		{"Chinese", &zh, "你好，\n世界。\n我用iPhone。"},
+		{"English", &en, "Hello there\n3.5kg"},
+		{"Combined", &combined, "你好，\n世界。\nHello there\n我用iPhone。\n3.5kg"},
+		{"Bilingual", &bilingual, "我用iPhone。"},
+		{"Numeric", &numeric, "3.5kg"},
+	}
+	for _, tt := range tests {
+		if got := tt.got.String(); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWriteStreamsSkipsNilAndReportsErrors(t *testing.T) {
+	result := ProcessText("你好。", DefaultOptions())
+	var zh bytes.Buffer
+	if err := WriteStreams(WriterSet{Chinese: &zh}, result, DefaultOptions()); err != nil || zh.String() != "你好。" {
+		t.Errorf("WriteStreams = %v with %q, want only the Chinese stream", err, zh.String())
+	}
+	if err := WriteStreams(WriterSet{English: failingWriter{}}, result, DefaultOptions()); err == nil {
+		t.Error("WriteStreams to a failing writer succeeded")
+	}
+}
+
+func TestCombinedLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		configure func(o *Options)
+		want      []string
+	}{
+		{"lines", "你好，hello.\n世界\nworld\n2021", nil, []string{"你好，", "hello.", "世界", "world", "2021"}},
+		{"separator", "你好，hello.\n世界\nworld", func(o *Options) { o.CombinedSep = " ||| " },
+			[]string{"你好， ||| hello.", "世界", "world"}},
+		{"inline tags", "你好，hello.\n2021", func(o *Options) { o.InlineTags = true }, []string{"[zh]你好，", "[en]hello.", "2021"}},
+		{"tags and separator", "你好，hello.", func(o *Options) { o.InlineTags = true; o.CombinedSep = "\t" },
+			[]string{"[zh]你好，\t[en]hello."}},
+		{"blocks", "你好，世界。\nhello.\nworld\n2021\n再见。", func(o *Options) { o.CombinedMode = CombinedModeBlocks },
+			[]string{"# [zh]", "你好，", "世界。", "# [en]", "hello.", "world", "2021", "# [zh]", "再见。"}},
+		{"blocks ignore separator", "你好，hello.", func(o *Options) { o.CombinedMode = CombinedModeBlocks; o.CombinedSep = "|" },
+			[]string{"# [zh]", "你好，", "# [en]", "hello."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := withOptions(tt.configure)
+			got := CombinedLines(ProcessText(tt.input, opts).Sentences, opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CombinedLines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	input := "42\n第一段。\n第二句。\nFirst paragraph.\nSecond one.\n7\n第三段。"
+	opts := DefaultOptions()
+	var got [][]string
+	var scripts []Script
+	for _, block := range opts.Blocks(ProcessText(input, opts).Sentences) {
+		scripts = append(scripts, block.Script)
+		var texts []string
+		for _, s := range block.Sentences {
+			texts = append(texts, s.Text)
+		}
+		got = append(got, texts)
+	}
+	want := [][]string{{"42", "第一段。", "第二句。"}, {"First paragraph.", "Second one.", "7"}, {"第三段。"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Blocks = %q, want %q", got, want)
+	}
+	if wantScripts := []Script{ScriptChinese, ScriptEnglish, ScriptChinese}; !reflect.DeepEqual(scripts, wantScripts) {
+		t.Errorf("block scripts = %v, want %v", scripts, wantScripts)
+	}
+	if only := opts.Blocks([]Sentence{{Text: "42"}}); len(only) != 1 || only[0].Script != ScriptOther || BlockLabel(only[0].Script) != "# [other]" {
+		t.Errorf("Blocks of a number alone = %+v, want one [other] block", only)
+	}
+}
+
+func TestAlignedRows(t *testing.T) {
+	input := "你好。Hello. 世界。\n只有中文。\nOnly English\n42"
+	got := AlignedRows(ProcessText(input, withOptions(func(o *Options) { o.EnglishTokenizer = true })).Sentences, DefaultOptions())
+	want := [][2]string{{"你好。", "Hello."}, {"世界。", ""}, {"只有中文。", ""}, {"", "Only English"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AlignedRows = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ljg-cqu/txt-sentencers_cn/sentencer"
+)
+
+// serveResponse is the JSON body returned by the /process endpoint.
+type serveResponse struct {
+	SchemaVersion int      `json:"schema_version"`
+	Combined      []string `json:"combined"`
+	Chinese       []string `json:"chinese"`
+	English       []string `json:"english"`
+	Lines         int      `json:"lines"`
+	Sentences     int      `json:"sentences"`
+}
+
+// newServeHandler returns the handler of -serve: POST /process splits the UTF-8 request body with
+// opts and answers with its streams as JSON, GET /healthz answers "ok" and GET /metrics exposes
+// the processing metrics of all requests. Bodies larger than maxBytes are refused with 413.
+func newServeHandler(opts sentencer.Options, maxBytes int64) http.Handler {
+	metrics := &processingMetrics{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok\n")
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		metrics.write(w)
+	})
+	mux.HandleFunc("/process", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+			metrics.addErrors(1)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, fmt.Sprintf("request body exceeds %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+			} else {
+				http.Error(w, "reading request body: "+err.Error(), http.StatusBadRequest)
+			}
+			metrics.addErrors(1)
+			return
+		}
+
+		start := time.Now()
+		result, err := sentencer.ProcessTextContext(r.Context(), string(body), opts)
+		if err != nil {
+			// The client went away; nobody is left to answer
+			return
+		}
+		metrics.add(result.Stats, time.Since(start))
+		data, err := marshalJSON(serveResponse{
+			SchemaVersion: schemaVersion,
+			Combined:      nonNil(result.Combined),
+			Chinese:       nonNil(result.Chinese),
+			English:       nonNil(result.English),
+			Lines:         result.Stats.Lines,
+			Sentences:     result.Stats.Sentences,
+		}, 0)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			metrics.addErrors(1)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(data, '\n'))
+	})
+	return mux
+}
+
+// nonNil returns lines, or an empty slice when it is nil, so empty streams encode as [] and not null.
+func nonNil(lines []string) []string {
+	if lines == nil {
+		return []string{}
+	}
+	return lines
+}
+
+// serve runs the -serve HTTP server on addr until it fails.
+func serve(addr string, opts sentencer.Options, maxBytes int64) error {
+	fmt.Printf("Serving on %s: POST text to /process, GET /healthz and /metrics\n", addr)
+	return http.ListenAndServe(addr, newServeHandler(opts, maxBytes))
+}
@@ -0,0 +1,68 @@
+//go:build sqlite
+
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+
+	"github.com/ljg-cqu/txt-sentencers_cn/sentencer"
+	_ "modernc.org/sqlite" // Pure-Go driver, so the build needs no C toolchain
+)
+
+func init() {
+	tableFormats[formatSQLite].write = writeSQLiteDB
+}
+
+const sqliteSchema = `CREATE TABLE sentences (
+	id          INTEGER PRIMARY KEY,
+	lang        TEXT NOT NULL,
+	text        TEXT NOT NULL,
+	source_file TEXT NOT NULL,
+	source_line INTEGER NOT NULL
+)`
+
+// writeSQLiteDB replaces the database at path with a new one holding every sentence of groups,
+// inserted in a single transaction.
+func writeSQLiteDB(path string, groups []fileSentences, opts sentencer.Options) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once committed
+	insert, err := tx.Prepare("INSERT INTO sentences (lang, text, source_file, source_line) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	for _, g := range groups {
+		for _, s := range g.sentences {
+			if _, err := insert.Exec(languageCode(opts.Classify(s.Text)), s.Text, g.path, s.SourceLine); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if err := db.Close(); err != nil {
+		return err
+	}
+	if outputFileMode != 0 {
+		return os.Chmod(path, outputFileMode) // The driver creates the file itself
+	}
+	return nil
+}
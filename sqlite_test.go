@@ -0,0 +1,50 @@
+//go:build sqlite
+
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestSQLiteOutput(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFile(t, "a.txt", "你好。Hello.\n")
+	writeFile(t, "b.txt", "\n2021\n")
+
+	type row struct {
+		lang, text, sourceFile string
+		sourceLine             int
+	}
+	want := []row{{"zh", "你好。", "a.txt", 1}, {"en", "Hello.", "a.txt", 1}, {"", "2021", "b.txt", 2}}
+	// The second run replaces the database rather than adding to it
+	for run := 1; run <= 2; run++ {
+		mustRun(t, 0, "-format", "sqlite", "-db", "out.db", "a.txt", "b.txt")
+
+		db, err := sql.Open("sqlite", "out.db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := db.Query("SELECT lang, text, source_file, source_line FROM sentences ORDER BY id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.lang, &r.text, &r.sourceFile, &r.sourceLine); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, r)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		db.Close()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("run %d: sentences = %+v, want %+v", run, got, want)
+		}
+	}
+}